@@ -0,0 +1,10 @@
+module github.com/supertokens/supertokens-go/echo
+
+go 1.13
+
+require (
+	github.com/labstack/echo/v4 v4.1.17
+	github.com/supertokens/supertokens-go v1.5.0
+)
+
+replace github.com/supertokens/supertokens-go => ../
@@ -0,0 +1,178 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/supertokens/supertokens-go/supertokens"
+	"github.com/supertokens/supertokens-go/supertokens/core"
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+// MiddlewareOptions add key value params for the routes protected by MiddlewareWithOptions
+type MiddlewareOptions struct {
+	// AntiCsrfCheck overrides doing the anti-csrf check for all requests except GET requests
+	AntiCsrfCheck *bool
+	// SessionOptional lets requests without a valid session through. GetSessionFromRequest returns nil for them
+	SessionOptional bool
+	// OnError replaces HandleErrorAndRespond for errors of these routes
+	OnError func(error, echo.Context) error
+	// SkipClaimValidation lets sessions through whose SessionClaims are stale or invalid
+	SkipClaimValidation bool
+}
+
+// Middleware for verifying and refreshing session.
+func Middleware(condition ...bool) echo.MiddlewareFunc {
+	options := MiddlewareOptions{}
+	if len(condition) == 1 {
+		options.AntiCsrfCheck = &condition[0]
+	}
+	return MiddlewareWithOptions(options)
+}
+
+// MiddlewareWithOptions for verifying and refreshing session with options for a group of routes
+func MiddlewareWithOptions(options MiddlewareOptions) echo.MiddlewareFunc {
+	handleError := HandleErrorAndRespond
+	if options.OnError != nil {
+		handleError = options.OnError
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			actualSession, err := supertokens.HandleSession(c.Response(), c.Request(), supertokens.MiddlewareOptions{
+				AntiCsrfCheck:       options.AntiCsrfCheck,
+				SessionOptional:     options.SessionOptional,
+				SkipClaimValidation: options.SkipClaimValidation,
+			})
+			if err != nil {
+				return handleError(err, c)
+			}
+			if actualSession != nil {
+				session := Session{
+					actualSession: actualSession,
+				}
+				c.Set(sessionContext, &session)
+			}
+			return next(c)
+		}
+	}
+}
+
+// HandleErrorAndRespond returns the error that the error handlers create for err. Echo's HTTPErrorHandler
+// then writes it to the response
+func HandleErrorAndRespond(err error, c echo.Context) error {
+	if errors.IsUnauthorizedError(err) {
+		return onUnauthorizedErrorHandler(err, c)
+	} else if errors.IsTryRefreshTokenError(err) {
+		return onTryRefreshTokenErrorHandler(err, c)
+	} else if errors.IsTokenTheftDetectedError(err) {
+		actualError := err.(errors.TokenTheftDetectedError)
+		return onTokenTheftDetectedErrorHandler(actualError.SessionHandle, actualError.UserID, c)
+	} else if errors.IsForbiddenError(err) {
+		return onForbiddenErrorHandler(err, c)
+	} else if errors.IsInvalidClaimError(err) {
+		return onInvalidClaimErrorHandler(err, c)
+	} else if errors.IsReauthenticationRequiredError(err) {
+		return onReauthenticationRequiredHandler(err, c)
+	}
+	return onGeneralErrorHandler(err, c)
+}
+
+var onTokenTheftDetectedErrorHandler = defaultTokenTheftDetectedErrorHandler
+var onUnauthorizedErrorHandler = defaultUnauthorizedErrorHandler
+var onTryRefreshTokenErrorHandler = defaultTryRefreshTokenErrorHandler
+var onGeneralErrorHandler = defaultGeneralErrorHandler
+var onForbiddenErrorHandler = defaultForbiddenErrorHandler
+var onInvalidClaimErrorHandler = defaultInvalidClaimErrorHandler
+var onReauthenticationRequiredHandler = defaultReauthenticationRequiredHandler
+
+// OnTokenTheftDetected function to override default behaviour of handling token thefts
+func OnTokenTheftDetected(handler func(sessionHandle string, userID string, c echo.Context) error) {
+	onTokenTheftDetectedErrorHandler = handler
+}
+
+// OnUnauthorized function to override default behaviour of handling Unauthorized error
+func OnUnauthorized(handler func(error, echo.Context) error) {
+	onUnauthorizedErrorHandler = handler
+}
+
+// OnTryRefreshToken function to override default behaviour of handling try refresh token errors
+func OnTryRefreshToken(handler func(error, echo.Context) error) {
+	onTryRefreshTokenErrorHandler = handler
+}
+
+// OnGeneralError function to override default behaviour of handling general errors
+func OnGeneralError(handler func(error, echo.Context) error) {
+	onGeneralErrorHandler = handler
+}
+
+// OnForbidden function to override default behaviour of handling forbidden errors
+func OnForbidden(handler func(error, echo.Context) error) {
+	onForbiddenErrorHandler = handler
+}
+
+// OnInvalidClaim function to override default behaviour of handling invalid claim errors
+func OnInvalidClaim(handler func(error, echo.Context) error) {
+	onInvalidClaimErrorHandler = handler
+}
+
+// OnReauthenticationRequired function to override default behaviour of handling reauthentication required errors
+func OnReauthenticationRequired(handler func(error, echo.Context) error) {
+	onReauthenticationRequiredHandler = handler
+}
+
+func defaultTokenTheftDetectedErrorHandler(sessionHandle string, userID string, c echo.Context) error {
+	handshakeInfo, handshakeInfoError := core.GetHandshakeInfoInstance()
+	if handshakeInfoError != nil {
+		return onGeneralErrorHandler(handshakeInfoError, c)
+	}
+	supertokens.RevokeSessionAfterTokenTheft(sessionHandle, userID)
+	return echo.NewHTTPError(handshakeInfo.SessionExpiredStatusCode, "token theft detected")
+}
+
+func defaultUnauthorizedErrorHandler(err error, c echo.Context) error {
+	handshakeInfo, handshakeInfoError := core.GetHandshakeInfoInstance()
+	if handshakeInfoError != nil {
+		return onGeneralErrorHandler(handshakeInfoError, c)
+	}
+	return echo.NewHTTPError(handshakeInfo.SessionExpiredStatusCode, "Unauthorized: "+err.Error()).SetInternal(err)
+}
+
+func defaultTryRefreshTokenErrorHandler(err error, c echo.Context) error {
+	handshakeInfo, handshakeInfoError := core.GetHandshakeInfoInstance()
+	if handshakeInfoError != nil {
+		return onGeneralErrorHandler(handshakeInfoError, c)
+	}
+	return echo.NewHTTPError(handshakeInfo.SessionExpiredStatusCode, "try refresh token: "+err.Error()).SetInternal(err)
+}
+
+func defaultForbiddenErrorHandler(err error, c echo.Context) error {
+	return echo.NewHTTPError(http.StatusForbidden, "Forbidden: "+err.Error()).SetInternal(err)
+}
+
+func defaultInvalidClaimErrorHandler(err error, c echo.Context) error {
+	return echo.NewHTTPError(http.StatusForbidden, "Invalid claim: "+err.Error()).SetInternal(err)
+}
+
+func defaultReauthenticationRequiredHandler(err error, c echo.Context) error {
+	return echo.NewHTTPError(http.StatusForbidden, "Reauthentication required: "+err.Error()).SetInternal(err)
+}
+
+func defaultGeneralErrorHandler(err error, c echo.Context) error {
+	return echo.NewHTTPError(http.StatusInternalServerError, "Internal error: "+err.Error()).SetInternal(err)
+}
@@ -0,0 +1,236 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"io"
+	"log"
+
+	"github.com/labstack/echo/v4"
+	"github.com/supertokens/supertokens-go/supertokens"
+	"github.com/supertokens/supertokens-go/supertokens/core"
+)
+
+// SessionContext string to get session struct from context if using Echo
+const sessionContext string = "supertokens_session_key"
+
+// ConfigMap add key value params for session behaviour
+type ConfigMap = supertokens.ConfigMap
+
+// Config used to set locations of SuperTokens instances
+func Config(config ConfigMap) {
+	supertokens.Config(config)
+}
+
+// NewStandardLogger returns a Logger that writes debug events to a *log.Logger
+func NewStandardLogger(logger *log.Logger) core.Logger {
+	return supertokens.NewStandardLogger(logger)
+}
+
+// NewFileHandshakeStore returns a HandshakeStore that keeps the handshake info in a file at path
+func NewFileHandshakeStore(path string) core.HandshakeStore {
+	return supertokens.NewFileHandshakeStore(path)
+}
+
+// NewInMemoryHandshakeStore returns a HandshakeStore that keeps the handshake info for the lifetime of the process
+func NewInMemoryHandshakeStore() core.HandshakeStore {
+	return supertokens.NewInMemoryHandshakeStore()
+}
+
+// CreateNewSession function used to create a new SuperTokens session
+func CreateNewSession(c echo.Context, userID string,
+	payload ...map[string]interface{}) (Session, error) {
+	actualSession, err := supertokens.CreateNewSessionWithRequest(c.Response(), c.Request(), userID, payload...)
+	if err != nil {
+		return Session{}, err
+	}
+	return Session{
+		actualSession: &actualSession,
+	}, nil
+}
+
+// CreateImpersonationSession function used to create a session for targetUserID that is used by actorUserID
+func CreateImpersonationSession(c echo.Context, targetUserID string, actorUserID string,
+	payload ...map[string]interface{}) (Session, error) {
+	actualSession, err := supertokens.CreateImpersonationSessionWithRequest(c.Response(), c.Request(), targetUserID,
+		actorUserID, payload...)
+	if err != nil {
+		return Session{}, err
+	}
+	return Session{
+		actualSession: &actualSession,
+	}, nil
+}
+
+// GetSession function used to verify a session
+func GetSession(c echo.Context, doAntiCsrfCheck bool) (Session, error) {
+	actualSession, err := supertokens.GetSession(c.Response(), c.Request(), doAntiCsrfCheck)
+	if err != nil {
+		return Session{}, err
+	}
+	return Session{
+		actualSession: &actualSession,
+	}, nil
+}
+
+// RefreshSession function used to refresh a session
+func RefreshSession(c echo.Context) (Session, error) {
+	actualSession, err := supertokens.RefreshSession(c.Response(), c.Request())
+	if err != nil {
+		return Session{}, err
+	}
+	return Session{
+		actualSession: &actualSession,
+	}, nil
+}
+
+// GetSessionForWebSocket function used to verify the session of a WebSocket upgrade request. There is no anti-csrf
+// check, so the Origin header is checked against WebSocketAllowedOrigins instead
+func GetSessionForWebSocket(c echo.Context) (Session, error) {
+	actualSession, err := supertokens.GetSessionForWebSocket(c.Response(), c.Request())
+	if err != nil {
+		return Session{}, err
+	}
+	return Session{
+		actualSession: &actualSession,
+	}, nil
+}
+
+// TrackConnection function used to close connection when its session is revoked in this process or its access
+// token expires. The returned function stops tracking and has to be called once the connection is closed
+func TrackConnection(session Session, connection io.Closer) func() {
+	return supertokens.TrackConnection(*session.actualSession, connection)
+}
+
+// GetTrackedConnectionCount function used to get the number of open connections tracked for a session
+func GetTrackedConnectionCount(sessionHandle string) int {
+	return supertokens.GetTrackedConnectionCount(sessionHandle)
+}
+
+// RevokeAllSessionsForUser function used to revoke all sessions for a user
+func RevokeAllSessionsForUser(userID string) ([]string, error) {
+	return supertokens.RevokeAllSessionsForUser(userID)
+}
+
+// GetAllSessionHandlesForUser function used to get all sessions for a user
+func GetAllSessionHandlesForUser(userID string) ([]string, error) {
+	return supertokens.GetAllSessionHandlesForUser(userID)
+}
+
+// RevokeSessionsForUsers function used to revoke all sessions of many users
+func RevokeSessionsForUsers(userIDs []string) (map[string][]string, error) {
+	return supertokens.RevokeSessionsForUsers(userIDs)
+}
+
+// GetDeviceMetadataFromSessionData function used to read device metadata from the session data of a session
+func GetDeviceMetadataFromSessionData(sessionData map[string]interface{}) *supertokens.DeviceMetadata {
+	return supertokens.GetDeviceMetadataFromSessionData(sessionData)
+}
+
+// GetAllSessionsForUser function used to get information about all sessions of a user
+func GetAllSessionsForUser(userID string) ([]core.SessionInformation, error) {
+	return supertokens.GetAllSessionsForUser(userID)
+}
+
+// GetSessionInformation function used to get the user ID, session data, jwt payload and lifetime of a session
+func GetSessionInformation(sessionHandle string) (core.SessionInformation, error) {
+	return supertokens.GetSessionInformation(sessionHandle)
+}
+
+// RevokeSession function used to revoke a specific session
+func RevokeSession(sessionHandle string) (bool, error) {
+	return supertokens.RevokeSession(sessionHandle)
+}
+
+// RevokeMultipleSessions function used to revoke a list of sessions
+func RevokeMultipleSessions(sessionHandles []string) ([]string, error) {
+	return supertokens.RevokeMultipleSessions(sessionHandles)
+}
+
+// GetSessionData function used to get session data for the given handle
+func GetSessionData(sessionHandle string) (map[string]interface{}, error) {
+	return supertokens.GetSessionData(sessionHandle)
+}
+
+// UpdateSessionData function used to update session data for the given handle
+func UpdateSessionData(sessionHandle string, newSessionData map[string]interface{}) error {
+	return supertokens.UpdateSessionData(sessionHandle, newSessionData)
+}
+
+// SetRelevantHeadersForOptionsAPI function is used to set headers specific to SuperTokens for OPTIONS API
+func SetRelevantHeadersForOptionsAPI(c echo.Context) {
+	supertokens.SetRelevantHeadersForOptionsAPI(c.Response())
+}
+
+// GetCORSAllowedHeaders function is used to get header keys that are used by SuperTokens
+func GetCORSAllowedHeaders() []string {
+	return supertokens.GetCORSAllowedHeaders()
+}
+
+// GetJWTPayload function used to get jwt payload for the given handle
+func GetJWTPayload(sessionHandle string) (map[string]interface{}, error) {
+	return supertokens.GetJWTPayload(sessionHandle)
+}
+
+// UpdateJWTPayload function used to update jwt payload for the given handle
+func UpdateJWTPayload(sessionHandle string, newJWTPayload map[string]interface{}) error {
+	return supertokens.UpdateJWTPayload(sessionHandle, newJWTPayload)
+}
+
+// OnImpersonation function to get notified when an impersonation session is created, expires or is blocked
+func OnImpersonation(hook func(core.ImpersonationEvent)) {
+	supertokens.OnImpersonation(hook)
+}
+
+// OnSessionCreated function to get notified after a new session has been created
+func OnSessionCreated(hook func(core.SessionEvent)) {
+	supertokens.OnSessionCreated(hook)
+}
+
+// OnSessionRefreshed function to get notified after a session has been refreshed
+func OnSessionRefreshed(hook func(core.SessionEvent)) {
+	supertokens.OnSessionRefreshed(hook)
+}
+
+// OnSessionRevoked function to get notified after a session has been revoked
+func OnSessionRevoked(hook func(core.SessionEvent)) {
+	supertokens.OnSessionRevoked(hook)
+}
+
+// OnJWTPayloadUpdated function to get notified after the jwt payload of a session has been updated
+func OnJWTPayloadUpdated(hook func(core.SessionEvent)) {
+	supertokens.OnJWTPayloadUpdated(hook)
+}
+
+// OnDegradedModeChange function to get notified when sessions start or stop being verified without the core
+func OnDegradedModeChange(handler func(active bool, err error)) {
+	supertokens.OnDegradedModeChange(handler)
+}
+
+// GetDegradedModeStats function used to get counters about sessions verified without the core
+func GetDegradedModeStats() core.DegradedModeStats {
+	return supertokens.GetDegradedModeStats()
+}
+
+// GetSessionFromRequest returns the verified session object if present, otherwise returns nil
+func GetSessionFromRequest(c echo.Context) *Session {
+	value := c.Get(sessionContext)
+	if value == nil {
+		return nil
+	}
+	return value.(*Session)
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/supertokens/supertokens-go/internal/fakecore"
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+func startFakeCore() *httptest.Server {
+	server, _ := fakecore.Start()
+	onTokenTheftDetectedErrorHandler = defaultTokenTheftDetectedErrorHandler
+	onUnauthorizedErrorHandler = defaultUnauthorizedErrorHandler
+	onTryRefreshTokenErrorHandler = defaultTryRefreshTokenErrorHandler
+	onGeneralErrorHandler = defaultGeneralErrorHandler
+	onForbiddenErrorHandler = defaultForbiddenErrorHandler
+	onInvalidClaimErrorHandler = defaultInvalidClaimErrorHandler
+	onReauthenticationRequiredHandler = defaultReauthenticationRequiredHandler
+	Config(ConfigMap{
+		Hosts: server.URL,
+	})
+	return server
+}
+
+func newTestServer() *echo.Echo {
+	e := echo.New()
+	e.POST("/login", func(c echo.Context) error {
+		if _, err := CreateNewSession(c, c.QueryParam("userId")); err != nil {
+			return HandleErrorAndRespond(err, c)
+		}
+		return c.String(http.StatusOK, "")
+	})
+	e.GET("/user", func(c echo.Context) error {
+		return c.String(http.StatusOK, GetSessionFromRequest(c).GetUserID())
+	}, Middleware())
+	e.POST("/refresh", func(c echo.Context) error {
+		return c.String(http.StatusOK, GetSessionFromRequest(c).GetUserID())
+	}, Middleware())
+	e.GET("/optional", func(c echo.Context) error {
+		session := GetSessionFromRequest(c)
+		if session == nil {
+			return c.String(http.StatusOK, "anonymous")
+		}
+		return c.String(http.StatusOK, session.GetUserID())
+	}, MiddlewareWithOptions(MiddlewareOptions{SessionOptional: true}))
+	return e
+}
+
+func serve(e *echo.Echo, method string, path string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, nil)
+	for _, cookie := range cookies {
+		request.AddCookie(cookie)
+	}
+	response := httptest.NewRecorder()
+	e.ServeHTTP(response, request)
+	return response
+}
+
+func TestMiddlewareVerifiesAndRefreshesSession(t *testing.T) {
+	defer startFakeCore().Close()
+	e := newTestServer()
+
+	login := serve(e, "POST", "/login?userId=user1", nil)
+	if login.Code != http.StatusOK {
+		t.Fatal("login failed", login.Code, login.Body.String())
+	}
+	cookies := login.Result().Cookies()
+
+	user := serve(e, "GET", "/user", cookies)
+	if user.Code != http.StatusOK || user.Body.String() != "user1" {
+		t.Error("session was not verified", user.Code, user.Body.String())
+	}
+
+	refresh := serve(e, "POST", "/refresh", cookies)
+	if refresh.Code != http.StatusOK || refresh.Body.String() != "user1" {
+		t.Error("session was not refreshed", refresh.Code, refresh.Body.String())
+	}
+	refreshedCookies := refresh.Result().Cookies()
+	if len(refreshedCookies) == 0 || refreshedCookies[0].Value == cookies[0].Value {
+		t.Error("new tokens were not set", refreshedCookies)
+	}
+}
+
+func TestMiddlewareWithOptionalSession(t *testing.T) {
+	defer startFakeCore().Close()
+	e := newTestServer()
+
+	anonymous := serve(e, "GET", "/optional", nil)
+	if anonymous.Code != http.StatusOK || anonymous.Body.String() != "anonymous" {
+		t.Error("optional session was not let through", anonymous.Code, anonymous.Body.String())
+	}
+	cookies := serve(e, "POST", "/login?userId=user1", nil).Result().Cookies()
+	withSession := serve(e, "GET", "/optional", cookies)
+	if withSession.Code != http.StatusOK || withSession.Body.String() != "user1" {
+		t.Error("optional session was not verified", withSession.Code, withSession.Body.String())
+	}
+}
+
+func TestMiddlewareReturnsHTTPErrors(t *testing.T) {
+	defer startFakeCore().Close()
+	e := newTestServer()
+
+	missingSession := serve(e, "GET", "/user", nil)
+	if missingSession.Code != 401 {
+		t.Error("incorrect status for missing session", missingSession.Code)
+	}
+	var body map[string]interface{}
+	json.NewDecoder(missingSession.Body).Decode(&body)
+	if body["message"] != "Unauthorized: idRefreshToken missing" {
+		t.Error("incorrect error message", body)
+	}
+
+	unknownRefreshToken := serve(e, "POST", "/refresh", []*http.Cookie{{Name: "sRefreshToken", Value: "unknown"}})
+	if unknownRefreshToken.Code != 401 {
+		t.Error("incorrect status for unknown refresh token", unknownRefreshToken.Code)
+	}
+
+	OnUnauthorized(func(err error, c echo.Context) error {
+		return echo.NewHTTPError(http.StatusForbidden, "custom")
+	})
+	custom := serve(e, "GET", "/user", nil)
+	if custom.Code != http.StatusForbidden {
+		t.Error("custom error handler was not used", custom.Code)
+	}
+}
+
+func TestForbiddenErrorsReturn403(t *testing.T) {
+	defer startFakeCore().Close()
+	e := newTestServer()
+	e.GET("/error", func(c echo.Context) error {
+		if c.QueryParam("type") == "forbidden" {
+			return HandleErrorAndRespond(errors.ForbiddenError{Msg: "forbidden"}, c)
+		}
+		return HandleErrorAndRespond(errors.ReauthenticationRequiredError{Msg: "reauthenticate"}, c)
+	})
+
+	for _, errorType := range []string{"forbidden", "reauthenticate"} {
+		if response := serve(e, "GET", "/error?type="+errorType, nil); response.Code != http.StatusForbidden {
+			t.Error("incorrect status", errorType, response.Code)
+		}
+	}
+	OnReauthenticationRequired(func(err error, c echo.Context) error {
+		return c.String(http.StatusUnauthorized, "custom")
+	})
+	if custom := serve(e, "GET", "/error", nil); custom.Code != http.StatusUnauthorized {
+		t.Error("custom reauthentication handler was not used", custom.Code)
+	}
+}
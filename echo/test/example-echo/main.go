@@ -0,0 +1,273 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/supertokens/supertokens-go/echo/supertokens"
+	"github.com/supertokens/supertokens-go/supertokens/core"
+)
+
+var noOfTimesGetSessionCalledDuringTest int = 0
+var noOfTimesRefreshCalledDuringTest int = 0
+
+func main() {
+	supertokens.Config(supertokens.ConfigMap{
+		Hosts:          "http://localhost:9000",
+		CookieSameSite: "lax",
+	})
+	e := echo.New()
+
+	// it's important to set CORS before any route. Otherwise it will not work
+	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins:     []string{"http://localhost.org:8080"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "HEAD", "OPTIONS"},
+		AllowHeaders:     append([]string{"Content-Type"}, supertokens.GetCORSAllowedHeaders()...),
+		AllowCredentials: true,
+	}))
+	e.Any("/login", login)
+	e.Any("/testUserConfig", testUserConfig)
+	e.Any("/multipleInterceptors", multipleInterceptors)
+	e.Any("/", defaultHandler, supertokens.Middleware())
+	e.Any("/beforeeach", beforeeach)
+	e.Any("/testing", testing)
+	e.Any("/logout", logout, supertokens.Middleware())
+	e.Any("/revokeAll", revokeAll, supertokens.Middleware())
+	e.Any("/refresh", refresh, supertokens.Middleware())
+	e.Any("/refreshCalledTime", refreshCalledTime)
+	e.Any("/getSessionCalledTime", getSessionCalledTime)
+	e.Any("/ping", ping)
+	e.Any("/testHeader", testHeader)
+	e.Any("/checkDeviceInfo", checkDeviceInfo)
+	e.Any("/checkAllowCredentials", checkAllowCredentials)
+	e.Any("/testError", testError)
+	e.Any("/index.html", index)
+	e.Any("/fail", fail)
+	e.Any("/update-jwt", updateJwt, supertokens.Middleware())
+	supertokens.OnTryRefreshToken(customOnTryRefreshTokenError)
+	supertokens.OnUnauthorized(customOnUnauthorizedError)
+	supertokens.OnGeneralError(customOnGeneralError)
+	port := "8080"
+	if len(os.Args) == 2 {
+		port = os.Args[1]
+	}
+	e.Logger.Fatal(e.Start("0.0.0.0:" + port))
+}
+
+func fail(c echo.Context) error {
+	return c.String(http.StatusNotFound, "")
+}
+
+func index(c echo.Context) error {
+	dat, _ := ioutil.ReadFile("./static/index.html")
+	return c.HTMLBlob(http.StatusOK, dat)
+}
+
+func login(c echo.Context) error {
+	if c.Request().Method != "POST" {
+		return c.String(http.StatusOK, "incorrect Method, requires POST")
+	}
+
+	var body map[string]interface{}
+	err := json.NewDecoder(c.Request().Body).Decode(&body)
+	if err != nil {
+		return c.String(http.StatusOK, "error when parsing body")
+	}
+	userID := body["userId"].(string)
+	_, err = supertokens.CreateNewSession(c, userID)
+
+	if err != nil {
+		return supertokens.HandleErrorAndRespond(err, c)
+	}
+	return c.String(http.StatusOK, userID)
+}
+
+func testUserConfig(c echo.Context) error {
+	if c.Request().Method != "POST" {
+		return c.String(http.StatusOK, "incorrect Method, requires POST")
+	}
+	return c.String(http.StatusOK, "")
+}
+
+func multipleInterceptors(c echo.Context) error {
+	request := c.Request()
+	if request.Method != "POST" {
+		return c.String(http.StatusOK, "incorrect Method, requires POST")
+	}
+	interceptorheader2 := request.Header.Get("interceptorheader2")
+	interceptorheader1 := request.Header.Get("interceptorheader1")
+
+	var resp string
+	if interceptorheader2 != "" && interceptorheader1 != "" {
+		resp = "success"
+	} else {
+		resp = "failure"
+	}
+	return c.String(http.StatusOK, resp)
+}
+
+func defaultHandler(c echo.Context) error {
+	if c.Request().Method != "GET" {
+		return c.String(http.StatusOK, "incorrect Method, requires GET")
+	}
+	noOfTimesGetSessionCalledDuringTest++
+	var session *supertokens.Session = supertokens.GetSessionFromRequest(c)
+	return c.String(http.StatusOK, session.GetUserID())
+}
+
+func updateJwt(c echo.Context) error {
+	request := c.Request()
+	if request.Method == "GET" {
+		session := supertokens.GetSessionFromRequest(c)
+		return c.JSON(http.StatusOK, session.GetJWTPayload())
+	} else if request.Method == "POST" {
+		var body map[string]interface{}
+		err := json.NewDecoder(request.Body).Decode(&body)
+		if err != nil {
+			return c.String(http.StatusOK, "error when parsing the body")
+		}
+		session := supertokens.GetSessionFromRequest(c)
+		session.UpdateJWTPayload(body)
+		return c.JSON(http.StatusOK, session.GetJWTPayload())
+	}
+	return c.String(http.StatusOK, "incorrect Method, requires POST or GET")
+}
+
+func beforeeach(c echo.Context) error {
+	if c.Request().Method != "POST" {
+		return c.String(http.StatusOK, "incorrect Method, requires POST")
+	}
+	noOfTimesRefreshCalledDuringTest = 0
+	noOfTimesGetSessionCalledDuringTest = 0
+	core.ResetHandshakeInfo()
+	return c.String(http.StatusOK, "")
+}
+
+func testing(c echo.Context) error {
+	value := c.Request().Header.Get("testing")
+	if value != "" {
+		c.Response().Header().Set("testing", value)
+	}
+	return c.String(http.StatusOK, "success")
+}
+
+func logout(c echo.Context) error {
+	if c.Request().Method != "POST" {
+		return c.String(http.StatusOK, "incorrect Method, requires POST")
+	}
+
+	session := supertokens.GetSessionFromRequest(c)
+	err := session.RevokeSession()
+	if err != nil {
+		return supertokens.HandleErrorAndRespond(err, c)
+	}
+	return c.String(http.StatusOK, "success")
+}
+
+func revokeAll(c echo.Context) error {
+	if c.Request().Method != "POST" {
+		return c.String(http.StatusOK, "incorrect Method, requires POST")
+	}
+	session := supertokens.GetSessionFromRequest(c)
+	userID := session.GetUserID()
+	supertokens.RevokeAllSessionsForUser(userID)
+	return c.String(http.StatusOK, "success")
+}
+
+func refresh(c echo.Context) error {
+	if c.Request().Method != "POST" {
+		return c.String(http.StatusOK, "incorrect Method, requires POST")
+	}
+	noOfTimesRefreshCalledDuringTest++
+	return c.String(http.StatusOK, "refresh success")
+}
+
+func refreshCalledTime(c echo.Context) error {
+	if c.Request().Method != "GET" {
+		return c.String(http.StatusOK, "incorrect Method, requires GET")
+	}
+	return c.String(http.StatusOK, strconv.Itoa(noOfTimesRefreshCalledDuringTest))
+}
+
+func getSessionCalledTime(c echo.Context) error {
+	if c.Request().Method != "GET" {
+		return c.String(http.StatusOK, "incorrect Method, requires GET")
+	}
+	return c.String(http.StatusOK, strconv.Itoa(noOfTimesGetSessionCalledDuringTest))
+}
+
+func ping(c echo.Context) error {
+	if c.Request().Method != "GET" {
+		return c.String(http.StatusOK, "incorrect Method, requires GET")
+	}
+	return c.String(http.StatusOK, "")
+}
+
+func testHeader(c echo.Context) error {
+	if c.Request().Method != "GET" {
+		return c.String(http.StatusOK, "incorrect Method, requires GET")
+	}
+	testheader := c.Request().Header.Get("st-custom-header")
+	success := testheader != ""
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": success,
+	})
+}
+
+func checkDeviceInfo(c echo.Context) error {
+	request := c.Request()
+	if request.Method != "GET" {
+		return c.String(http.StatusOK, "incorrect Method, requires GET")
+	}
+	sdkName := request.Header.Get("supertokens-sdk-name")
+	sdkVersion := request.Header.Get("supertokens-sdk-version")
+	return c.String(http.StatusOK, strconv.FormatBool(sdkName == "website" && sdkVersion != ""))
+}
+
+func checkAllowCredentials(c echo.Context) error {
+	request := c.Request()
+	if request.Method != "POST" {
+		return c.String(http.StatusOK, "incorrect Method, requires POST")
+	}
+	return c.String(http.StatusOK, strconv.FormatBool(request.Header.Get("allow-credentials") != ""))
+}
+
+func testError(c echo.Context) error {
+	if c.Request().Method != "GET" {
+		return c.String(http.StatusOK, "incorrect Method, requires GET")
+	}
+	return c.String(http.StatusInternalServerError, "test error message")
+}
+
+func customOnTryRefreshTokenError(err error, c echo.Context) error {
+	return c.String(401, "")
+}
+
+func customOnUnauthorizedError(err error, c echo.Context) error {
+	return c.String(401, "")
+}
+
+func customOnGeneralError(err error, c echo.Context) error {
+	return c.String(http.StatusInternalServerError, "Something went wrong")
+}
@@ -0,0 +1,10 @@
+module github.com/supertokens/supertokens-go/fiber
+
+go 1.13
+
+require (
+	github.com/gofiber/fiber v1.14.6
+	github.com/supertokens/supertokens-go v1.5.0
+)
+
+replace github.com/supertokens/supertokens-go => ../
@@ -0,0 +1,153 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"github.com/gofiber/fiber"
+	"github.com/supertokens/supertokens-go/supertokens"
+	"github.com/supertokens/supertokens-go/supertokens/core"
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+// Middleware for verifying and refreshing session.
+func Middleware(condition ...bool) fiber.Handler {
+	options := supertokens.MiddlewareOptions{}
+	if len(condition) == 1 {
+		options.AntiCsrfCheck = &condition[0]
+	}
+	return func(c *fiber.Ctx) {
+		actualSession, err := supertokens.HandleSessionWithWrapper(wrapResponse(c), wrapRequest(c), options)
+		if err != nil {
+			HandleErrorAndRespond(err, c)
+			return
+		}
+		if actualSession != nil {
+			session := Session{
+				actualSession: actualSession,
+			}
+			c.Locals(sessionContext, &session)
+		}
+		c.Next()
+	}
+}
+
+// HandleErrorAndRespond if error handlers are provided, then uses those, else does default error handling depending on the type of error
+func HandleErrorAndRespond(err error, c *fiber.Ctx) {
+	if errors.IsUnauthorizedError(err) {
+		onUnauthorizedErrorHandler(err, c)
+	} else if errors.IsTryRefreshTokenError(err) {
+		onTryRefreshTokenErrorHandler(err, c)
+	} else if errors.IsTokenTheftDetectedError(err) {
+		actualError := err.(errors.TokenTheftDetectedError)
+		onTokenTheftDetectedErrorHandler(actualError.SessionHandle, actualError.UserID, c)
+	} else if errors.IsForbiddenError(err) {
+		onForbiddenErrorHandler(err, c)
+	} else if errors.IsInvalidClaimError(err) {
+		onInvalidClaimErrorHandler(err, c)
+	} else if errors.IsReauthenticationRequiredError(err) {
+		onReauthenticationRequiredHandler(err, c)
+	} else {
+		onGeneralErrorHandler(err, c)
+	}
+}
+
+var onTokenTheftDetectedErrorHandler = defaultTokenTheftDetectedErrorHandler
+var onUnauthorizedErrorHandler = defaultUnauthorizedErrorHandler
+var onTryRefreshTokenErrorHandler = defaultTryRefreshTokenErrorHandler
+var onGeneralErrorHandler = defaultGeneralErrorHandler
+var onForbiddenErrorHandler = defaultForbiddenErrorHandler
+var onInvalidClaimErrorHandler = defaultInvalidClaimErrorHandler
+var onReauthenticationRequiredHandler = defaultReauthenticationRequiredHandler
+
+// OnTokenTheftDetected function to override default behaviour of handling token thefts
+func OnTokenTheftDetected(handler func(sessionHandle string, userID string, c *fiber.Ctx)) {
+	onTokenTheftDetectedErrorHandler = handler
+}
+
+// OnUnauthorized function to override default behaviour of handling Unauthorized error
+func OnUnauthorized(handler func(error, *fiber.Ctx)) {
+	onUnauthorizedErrorHandler = handler
+}
+
+// OnTryRefreshToken function to override default behaviour of handling try refresh token errors
+func OnTryRefreshToken(handler func(error, *fiber.Ctx)) {
+	onTryRefreshTokenErrorHandler = handler
+}
+
+// OnGeneralError function to override default behaviour of handling general errors
+func OnGeneralError(handler func(error, *fiber.Ctx)) {
+	onGeneralErrorHandler = handler
+}
+
+// OnForbidden function to override default behaviour of handling forbidden errors
+func OnForbidden(handler func(error, *fiber.Ctx)) {
+	onForbiddenErrorHandler = handler
+}
+
+// OnInvalidClaim function to override default behaviour of handling invalid claim errors
+func OnInvalidClaim(handler func(error, *fiber.Ctx)) {
+	onInvalidClaimErrorHandler = handler
+}
+
+// OnReauthenticationRequired function to override default behaviour of handling reauthentication required errors
+func OnReauthenticationRequired(handler func(error, *fiber.Ctx)) {
+	onReauthenticationRequiredHandler = handler
+}
+
+func defaultTokenTheftDetectedErrorHandler(sessionHandle string, userID string, c *fiber.Ctx) {
+	handshakeInfo, handshakeInfoError := core.GetHandshakeInfoInstance()
+	if handshakeInfoError != nil {
+		onGeneralErrorHandler(handshakeInfoError, c)
+		return
+	}
+	c.Status(handshakeInfo.SessionExpiredStatusCode).SendString("token theft detected")
+	supertokens.RevokeSessionAfterTokenTheft(sessionHandle, userID)
+}
+
+func defaultUnauthorizedErrorHandler(err error, c *fiber.Ctx) {
+	handshakeInfo, handshakeInfoError := core.GetHandshakeInfoInstance()
+	if handshakeInfoError != nil {
+		onGeneralErrorHandler(handshakeInfoError, c)
+		return
+	}
+	c.Status(handshakeInfo.SessionExpiredStatusCode).SendString("Unauthorized: " + err.Error())
+}
+
+func defaultTryRefreshTokenErrorHandler(err error, c *fiber.Ctx) {
+	handshakeInfo, handshakeInfoError := core.GetHandshakeInfoInstance()
+	if handshakeInfoError != nil {
+		onGeneralErrorHandler(handshakeInfoError, c)
+		return
+	}
+	c.Status(handshakeInfo.SessionExpiredStatusCode).SendString("try refresh token: " + err.Error())
+}
+
+func defaultForbiddenErrorHandler(err error, c *fiber.Ctx) {
+	c.Status(403).SendString("Forbidden: " + err.Error())
+}
+
+func defaultInvalidClaimErrorHandler(err error, c *fiber.Ctx) {
+	c.Status(403).SendString("Invalid claim: " + err.Error())
+}
+
+func defaultReauthenticationRequiredHandler(err error, c *fiber.Ctx) {
+	c.Status(403).SendString("Reauthentication required: " + err.Error())
+}
+
+func defaultGeneralErrorHandler(err error, c *fiber.Ctx) {
+	c.Status(500).SendString("Internal error: " + err.Error())
+}
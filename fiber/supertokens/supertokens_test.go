@@ -0,0 +1,270 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber"
+	"github.com/supertokens/supertokens-go/internal/fakecore"
+	"github.com/supertokens/supertokens-go/supertokens"
+	"github.com/supertokens/supertokens-go/supertokens/core"
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+func startFakeCore() *httptest.Server {
+	server, _ := fakecore.Start()
+	onTokenTheftDetectedErrorHandler = defaultTokenTheftDetectedErrorHandler
+	onUnauthorizedErrorHandler = defaultUnauthorizedErrorHandler
+	onTryRefreshTokenErrorHandler = defaultTryRefreshTokenErrorHandler
+	onGeneralErrorHandler = defaultGeneralErrorHandler
+	onForbiddenErrorHandler = defaultForbiddenErrorHandler
+	onInvalidClaimErrorHandler = defaultInvalidClaimErrorHandler
+	onReauthenticationRequiredHandler = defaultReauthenticationRequiredHandler
+	Config(ConfigMap{
+		Hosts: server.URL,
+	})
+	return server
+}
+
+// result is the part of a response that has to be the same for the net/http and the fiber implementation
+type result struct {
+	Status  int
+	Body    string
+	Cookies []string
+	Headers map[string]string
+}
+
+func toResult(t *testing.T, response *http.Response) result {
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookies := []string{}
+	for _, cookie := range response.Cookies() {
+		cookies = append(cookies, cookie.Name+"="+cookie.Value+"; Path="+cookie.Path+
+			"; Expires="+cookie.Expires.UTC().String()+"; HttpOnly="+strconv.FormatBool(cookie.HttpOnly)+
+			"; Secure="+strconv.FormatBool(cookie.Secure)+"; SameSite="+strconv.Itoa(int(cookie.SameSite)))
+	}
+	sort.Strings(cookies)
+	headers := map[string]string{}
+	for _, key := range []string{"anti-csrf", "id-refresh-token", "Access-Control-Expose-Headers",
+		"Access-Control-Allow-Headers", "Access-Control-Allow-Credentials"} {
+		headers[key] = response.Header.Get(key)
+	}
+	return result{
+		Status:  response.StatusCode,
+		Body:    string(body),
+		Cookies: cookies,
+		Headers: headers,
+	}
+}
+
+type step struct {
+	method  string
+	path    string
+	cookies func(previous []*http.Cookie) []*http.Cookie
+}
+
+// steps are run in order. Each step gets the cookies set by the login step
+var steps = []step{
+	{"POST", "/login?userId=user1", nil},
+	{"GET", "/user", func(previous []*http.Cookie) []*http.Cookie { return previous }},
+	{"GET", "/user", nil},
+	{"GET", "/user", func(previous []*http.Cookie) []*http.Cookie {
+		return filterCookies(previous, "sIdRefreshToken")
+	}},
+	{"OPTIONS", "/user", nil},
+	{"POST", "/refresh", func(previous []*http.Cookie) []*http.Cookie {
+		return filterCookies(previous, "sRefreshToken")
+	}},
+	{"POST", "/refresh", func(previous []*http.Cookie) []*http.Cookie {
+		return filterCookies(previous, "sRefreshToken")
+	}},
+}
+
+func filterCookies(cookies []*http.Cookie, name string) []*http.Cookie {
+	filtered := []*http.Cookie{}
+	for _, cookie := range cookies {
+		if cookie.Name == name {
+			filtered = append(filtered, cookie)
+		}
+	}
+	return filtered
+}
+
+func runSteps(t *testing.T, do func(request *http.Request) *http.Response) []result {
+	defer startFakeCore().Close()
+	results := []result{}
+	var loginCookies []*http.Cookie
+	for _, step := range steps {
+		request := httptest.NewRequest(step.method, step.path, nil)
+		if step.cookies != nil {
+			for _, cookie := range step.cookies(loginCookies) {
+				request.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+			}
+		}
+		response := do(request)
+		if loginCookies == nil {
+			loginCookies = response.Cookies()
+		}
+		results = append(results, toResult(t, response))
+	}
+	return results
+}
+
+func newNetHTTPServer() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := supertokens.CreateNewSessionWithRequest(w, r, r.URL.Query().Get("userId")); err != nil {
+			supertokens.HandleErrorAndRespond(err, w)
+		}
+	})
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			supertokens.SetRelevantHeadersForOptionsAPI(w)
+		}
+		session := supertokens.GetSessionFromRequest(r)
+		if session != nil {
+			w.Write([]byte(session.GetUserID()))
+		}
+	}
+	mux.HandleFunc("/user", supertokens.Middleware(handler))
+	mux.HandleFunc("/refresh", supertokens.Middleware(handler))
+	return mux
+}
+
+func newFiberApp() *fiber.App {
+	app := fiber.New()
+	app.Post("/login", func(c *fiber.Ctx) {
+		if _, err := CreateNewSession(c, c.Query("userId")); err != nil {
+			HandleErrorAndRespond(err, c)
+		}
+	})
+	handler := func(c *fiber.Ctx) {
+		if c.Method() == "OPTIONS" {
+			SetRelevantHeadersForOptionsAPI(c)
+		}
+		session := GetSessionFromRequest(c)
+		if session != nil {
+			c.SendString(session.GetUserID())
+		}
+	}
+	app.Get("/user", Middleware(), handler)
+	app.Options("/user", Middleware(), handler)
+	app.Post("/refresh", Middleware(), handler)
+	return app
+}
+
+func TestParityWithNetHTTP(t *testing.T) {
+	server := newNetHTTPServer()
+	expected := runSteps(t, func(request *http.Request) *http.Response {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+		return response.Result()
+	})
+
+	app := newFiberApp()
+	actual := runSteps(t, func(request *http.Request) *http.Response {
+		response, err := app.Test(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response
+	})
+
+	for i := range steps {
+		if !reflect.DeepEqual(expected[i], actual[i]) {
+			t.Errorf("%s %s differs\nnet/http: %+v\nfiber:    %+v", steps[i].method, steps[i].path, expected[i], actual[i])
+		}
+	}
+	if expected[1].Body != "user1" || expected[5].Body != "user1" {
+		t.Error("session was not verified and refreshed", expected)
+	}
+}
+
+func TestCustomErrorHandler(t *testing.T) {
+	defer startFakeCore().Close()
+	OnUnauthorized(func(err error, c *fiber.Ctx) {
+		c.Status(http.StatusForbidden).SendString("custom")
+	})
+	response, err := newFiberApp().Test(httptest.NewRequest("GET", "/user", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusForbidden {
+		t.Error("custom error handler was not used", response.StatusCode)
+	}
+}
+
+func TestForbiddenErrorsReturn403(t *testing.T) {
+	defer startFakeCore().Close()
+	app := newFiberApp()
+	app.Get("/error", func(c *fiber.Ctx) {
+		if c.Query("type") == "forbidden" {
+			HandleErrorAndRespond(errors.ForbiddenError{Msg: "forbidden"}, c)
+			return
+		}
+		HandleErrorAndRespond(errors.ReauthenticationRequiredError{Msg: "reauthenticate"}, c)
+	})
+
+	for _, errorType := range []string{"forbidden", "reauthenticate"} {
+		response, err := app.Test(httptest.NewRequest("GET", "/error?type="+errorType, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.StatusCode != http.StatusForbidden {
+			t.Error("incorrect status", errorType, response.StatusCode)
+		}
+	}
+}
+
+func TestDeviceMetadataAndHooksGetTheRequest(t *testing.T) {
+	server := startFakeCore()
+	defer server.Close()
+	Config(ConfigMap{
+		Hosts:                   server.URL,
+		DeviceMetadataExtractor: supertokens.DefaultDeviceMetadataExtractor,
+	})
+	var metadata *supertokens.DeviceMetadata
+	var path string
+	OnSessionCreated(func(event core.SessionEvent) {
+		metadata = GetDeviceMetadataFromSessionData(event.SessionData)
+		if event.RequestWrapper != nil {
+			path = event.RequestWrapper.GetPath()
+		}
+	})
+	defer core.ResetSessionHooks()
+
+	request := httptest.NewRequest("POST", "/login?userId=user1", nil)
+	request.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) Chrome/120.0 Safari/537.36")
+	if _, err := newFiberApp().Test(request); err != nil {
+		t.Fatal(err)
+	}
+	if metadata == nil || metadata.Label != "Chrome on macOS" {
+		t.Error("device metadata was not stored", metadata)
+	}
+	if path != "/login" {
+		t.Error("session created hook did not get the request", path)
+	}
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gofiber/fiber"
+	"github.com/supertokens/supertokens-go/supertokens"
+)
+
+// values read from fasthttp are copied since fasthttp reuses its buffers after the handler returns
+
+type requestWrapper struct {
+	c *fiber.Ctx
+}
+
+func wrapRequest(c *fiber.Ctx) supertokens.RequestWrapper {
+	return requestWrapper{c: c}
+}
+
+func (wrapper requestWrapper) Context() context.Context {
+	return wrapper.c.Context()
+}
+
+func (wrapper requestWrapper) GetHeader(key string) string {
+	return string(wrapper.c.Fasthttp.Request.Header.Peek(key))
+}
+
+func (wrapper requestWrapper) GetCookie(key string) (string, bool) {
+	value := wrapper.c.Fasthttp.Request.Header.Cookie(key)
+	if value == nil {
+		return "", false
+	}
+	return string(value), true
+}
+
+func (wrapper requestWrapper) GetMethod() string {
+	return string(wrapper.c.Fasthttp.Method())
+}
+
+func (wrapper requestWrapper) GetPath() string {
+	return string(wrapper.c.Fasthttp.Path())
+}
+
+func (wrapper requestWrapper) GetRemoteAddr() string {
+	return wrapper.c.Fasthttp.RemoteAddr().String()
+}
+
+type responseWrapper struct {
+	c *fiber.Ctx
+}
+
+func wrapResponse(c *fiber.Ctx) supertokens.ResponseWrapper {
+	return responseWrapper{c: c}
+}
+
+func (wrapper responseWrapper) GetHeader(key string) string {
+	return string(wrapper.c.Fasthttp.Response.Header.Peek(key))
+}
+
+func (wrapper responseWrapper) SetHeader(key string, value string) {
+	wrapper.c.Set(key, value)
+}
+
+func (wrapper responseWrapper) SetCookie(cookie *http.Cookie) {
+	sameSite := "none"
+	if cookie.SameSite == http.SameSiteLaxMode {
+		sameSite = "lax"
+	} else if cookie.SameSite == http.SameSiteStrictMode {
+		sameSite = "strict"
+	}
+	wrapper.c.Cookie(&fiber.Cookie{
+		Name:     cookie.Name,
+		Value:    cookie.Value,
+		Path:     cookie.Path,
+		Domain:   cookie.Domain,
+		Expires:  cookie.Expires,
+		Secure:   cookie.Secure,
+		HTTPOnly: cookie.HttpOnly,
+		SameSite: sameSite,
+	})
+}
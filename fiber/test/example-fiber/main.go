@@ -0,0 +1,293 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber"
+	"github.com/supertokens/supertokens-go/fiber/supertokens"
+	"github.com/supertokens/supertokens-go/supertokens/core"
+)
+
+var noOfTimesGetSessionCalledDuringTest int = 0
+var noOfTimesRefreshCalledDuringTest int = 0
+
+func main() {
+	supertokens.Config(supertokens.ConfigMap{
+		Hosts:          "http://localhost:9000",
+		CookieSameSite: "lax",
+	})
+	app := fiber.New()
+
+	// it's important to set CORS before any route. Otherwise it will not work
+	app.Use(cors)
+	app.All("/login", login)
+	app.All("/testUserConfig", testUserConfig)
+	app.All("/multipleInterceptors", multipleInterceptors)
+	app.All("/", supertokens.Middleware(), defaultHandler)
+	app.All("/beforeeach", beforeeach)
+	app.All("/testing", testing)
+	app.All("/logout", supertokens.Middleware(), logout)
+	app.All("/revokeAll", supertokens.Middleware(), revokeAll)
+	app.All("/refresh", supertokens.Middleware(), refresh)
+	app.All("/refreshCalledTime", refreshCalledTime)
+	app.All("/getSessionCalledTime", getSessionCalledTime)
+	app.All("/ping", ping)
+	app.All("/testHeader", testHeader)
+	app.All("/checkDeviceInfo", checkDeviceInfo)
+	app.All("/checkAllowCredentials", checkAllowCredentials)
+	app.All("/testError", testError)
+	app.All("/index.html", index)
+	app.All("/fail", fail)
+	app.All("/update-jwt", supertokens.Middleware(), updateJwt)
+	supertokens.OnTryRefreshToken(customOnTryRefreshTokenError)
+	supertokens.OnUnauthorized(customOnUnauthorizedError)
+	supertokens.OnGeneralError(customOnGeneralError)
+	port := "8080"
+	if len(os.Args) == 2 {
+		port = os.Args[1]
+	}
+	app.Listen("0.0.0.0:" + port)
+}
+
+func cors(c *fiber.Ctx) {
+	c.Set("Access-Control-Allow-Origin", "http://localhost.org:8080")
+	c.Set("Access-Control-Allow-Credentials", "true")
+	if c.Method() == "OPTIONS" {
+		c.Set("Access-Control-Allow-Methods", "GET, POST, PUT, HEAD, OPTIONS")
+		c.Set("Access-Control-Allow-Headers",
+			strings.Join(append([]string{"Content-Type"}, supertokens.GetCORSAllowedHeaders()...), ", "))
+		c.SendStatus(204)
+		return
+	}
+	c.Next()
+}
+
+func fail(c *fiber.Ctx) {
+	c.Status(404).SendString("")
+}
+
+func index(c *fiber.Ctx) {
+	c.SendFile("./static/index.html")
+}
+
+func login(c *fiber.Ctx) {
+	if c.Method() != "POST" {
+		c.SendString("incorrect Method, requires POST")
+		return
+	}
+
+	var body map[string]interface{}
+	err := json.Unmarshal([]byte(c.Body()), &body)
+	if err != nil {
+		c.SendString("error when parsing body")
+		return
+	}
+	userID := body["userId"].(string)
+	_, err = supertokens.CreateNewSession(c, userID)
+
+	if err != nil {
+		supertokens.HandleErrorAndRespond(err, c)
+		return
+	}
+	c.SendString(userID)
+}
+
+func testUserConfig(c *fiber.Ctx) {
+	if c.Method() != "POST" {
+		c.SendString("incorrect Method, requires POST")
+		return
+	}
+	c.SendString("")
+}
+
+func multipleInterceptors(c *fiber.Ctx) {
+	if c.Method() != "POST" {
+		c.SendString("incorrect Method, requires POST")
+		return
+	}
+	interceptorheader2 := c.Get("interceptorheader2")
+	interceptorheader1 := c.Get("interceptorheader1")
+
+	var resp string
+	if interceptorheader2 != "" && interceptorheader1 != "" {
+		resp = "success"
+	} else {
+		resp = "failure"
+	}
+	c.SendString(resp)
+}
+
+func defaultHandler(c *fiber.Ctx) {
+	if c.Method() != "GET" {
+		c.SendString("incorrect Method, requires GET")
+		return
+	}
+	noOfTimesGetSessionCalledDuringTest++
+	var session *supertokens.Session = supertokens.GetSessionFromRequest(c)
+	c.SendString(session.GetUserID())
+}
+
+func updateJwt(c *fiber.Ctx) {
+	if c.Method() == "GET" {
+		session := supertokens.GetSessionFromRequest(c)
+		c.JSON(session.GetJWTPayload())
+	} else if c.Method() == "POST" {
+		var body map[string]interface{}
+		err := json.Unmarshal([]byte(c.Body()), &body)
+		if err != nil {
+			c.SendString("error when parsing the body")
+			return
+		}
+		session := supertokens.GetSessionFromRequest(c)
+		session.UpdateJWTPayload(body)
+		c.JSON(session.GetJWTPayload())
+	} else {
+		c.SendString("incorrect Method, requires POST or GET")
+	}
+}
+
+func beforeeach(c *fiber.Ctx) {
+	if c.Method() != "POST" {
+		c.SendString("incorrect Method, requires POST")
+		return
+	}
+	noOfTimesRefreshCalledDuringTest = 0
+	noOfTimesGetSessionCalledDuringTest = 0
+	core.ResetHandshakeInfo()
+	c.SendString("")
+}
+
+func testing(c *fiber.Ctx) {
+	value := c.Get("testing")
+	if value != "" {
+		c.Set("testing", value)
+	}
+	c.SendString("success")
+}
+
+func logout(c *fiber.Ctx) {
+	if c.Method() != "POST" {
+		c.SendString("incorrect Method, requires POST")
+		return
+	}
+
+	session := supertokens.GetSessionFromRequest(c)
+	err := session.RevokeSession()
+	if err != nil {
+		supertokens.HandleErrorAndRespond(err, c)
+		return
+	}
+	c.SendString("success")
+}
+
+func revokeAll(c *fiber.Ctx) {
+	if c.Method() != "POST" {
+		c.SendString("incorrect Method, requires POST")
+		return
+	}
+	session := supertokens.GetSessionFromRequest(c)
+	userID := session.GetUserID()
+	supertokens.RevokeAllSessionsForUser(userID)
+	c.SendString("success")
+}
+
+func refresh(c *fiber.Ctx) {
+	if c.Method() != "POST" {
+		c.SendString("incorrect Method, requires POST")
+		return
+	}
+	noOfTimesRefreshCalledDuringTest++
+	c.SendString("refresh success")
+}
+
+func refreshCalledTime(c *fiber.Ctx) {
+	if c.Method() != "GET" {
+		c.SendString("incorrect Method, requires GET")
+		return
+	}
+	c.SendString(strconv.Itoa(noOfTimesRefreshCalledDuringTest))
+}
+
+func getSessionCalledTime(c *fiber.Ctx) {
+	if c.Method() != "GET" {
+		c.SendString("incorrect Method, requires GET")
+		return
+	}
+	c.SendString(strconv.Itoa(noOfTimesGetSessionCalledDuringTest))
+}
+
+func ping(c *fiber.Ctx) {
+	if c.Method() != "GET" {
+		c.SendString("incorrect Method, requires GET")
+		return
+	}
+	c.SendString("")
+}
+
+func testHeader(c *fiber.Ctx) {
+	if c.Method() != "GET" {
+		c.SendString("incorrect Method, requires GET")
+		return
+	}
+	success := c.Get("st-custom-header") != ""
+	c.JSON(map[string]interface{}{
+		"success": success,
+	})
+}
+
+func checkDeviceInfo(c *fiber.Ctx) {
+	if c.Method() != "GET" {
+		c.SendString("incorrect Method, requires GET")
+		return
+	}
+	sdkName := c.Get("supertokens-sdk-name")
+	sdkVersion := c.Get("supertokens-sdk-version")
+	c.SendString(strconv.FormatBool(sdkName == "website" && sdkVersion != ""))
+}
+
+func checkAllowCredentials(c *fiber.Ctx) {
+	if c.Method() != "POST" {
+		c.SendString("incorrect Method, requires POST")
+		return
+	}
+	c.SendString(strconv.FormatBool(c.Get("allow-credentials") != ""))
+}
+
+func testError(c *fiber.Ctx) {
+	if c.Method() != "GET" {
+		c.SendString("incorrect Method, requires GET")
+		return
+	}
+	c.Status(500).SendString("test error message")
+}
+
+func customOnTryRefreshTokenError(err error, c *fiber.Ctx) {
+	c.Status(401).SendString("")
+}
+
+func customOnUnauthorizedError(err error, c *fiber.Ctx) {
+	c.Status(401).SendString("")
+}
+
+func customOnGeneralError(err error, c *fiber.Ctx) {
+	c.Status(500).SendString("Something went wrong")
+}
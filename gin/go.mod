@@ -5,6 +5,7 @@ go 1.13
 require (
 	github.com/gin-contrib/cors v1.3.1
 	github.com/gin-gonic/gin v1.6.3
-	github.com/gorilla/handlers v1.4.2 // indirect
-	github.com/supertokens/supertokens-go v1.3.1-0.20200910182647-9e3dcd7f0e68
+	github.com/supertokens/supertokens-go v1.5.0
 )
+
+replace github.com/supertokens/supertokens-go => ../
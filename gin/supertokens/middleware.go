@@ -18,25 +18,56 @@ package supertokens
 
 import (
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/supertokens/supertokens-go/supertokens"
+	"github.com/supertokens/supertokens-go/supertokens/core"
+	"github.com/supertokens/supertokens-go/supertokens/errors"
 )
 
+// SignOutPath is the path of the sign out API added by RegisterRoutes, relative to the router group
+const SignOutPath = "/signout"
+
+// MiddlewareOptions add key value params for the routes protected by MiddlewareWithOptions
+type MiddlewareOptions struct {
+	// AntiCsrfCheck overrides doing the anti-csrf check for all requests except GET requests
+	AntiCsrfCheck *bool
+	// SessionOptional lets requests without a valid session through. GetSessionFromRequest returns nil for them
+	SessionOptional bool
+	// OnError replaces HandleErrorAndRespond for errors of these routes. JSONErrorResponder can be used
+	OnError func(error, *gin.Context)
+	// SkipClaimValidation lets sessions through whose SessionClaims are stale or invalid
+	SkipClaimValidation bool
+}
+
 // Middleware for verifying and refreshing session.
 func Middleware(condition ...bool) func(*gin.Context) {
+	options := MiddlewareOptions{}
+	if len(condition) == 1 {
+		options.AntiCsrfCheck = &condition[0]
+	}
+	return MiddlewareWithOptions(options)
+}
+
+// MiddlewareWithOptions for verifying and refreshing session with options for a group of routes
+func MiddlewareWithOptions(options MiddlewareOptions) func(*gin.Context) {
+	handleError := HandleErrorAndRespond
+	if options.OnError != nil {
+		handleError = options.OnError
+	}
 	return func(c *gin.Context) {
-		var params = []interface{}{}
-		if len(condition) == 1 {
-			params = append(params, condition[0])
-		} else {
-			params = append(params, nil)
-		}
-		params = append(params, func(err error, w http.ResponseWriter) {
-			c.Abort()
-			supertokens.HandleErrorAndRespond(err, w)
+		middleware := supertokens.NewMiddleware(supertokens.MiddlewareOptions{
+			AntiCsrfCheck:       options.AntiCsrfCheck,
+			SessionOptional:     options.SessionOptional,
+			SkipClaimValidation: options.SkipClaimValidation,
+			OnError: func(err error, w http.ResponseWriter) {
+				c.Abort()
+				handleError(err, c)
+			},
 		})
-		handler := supertokens.Middleware(func(w http.ResponseWriter, r *http.Request) {
+		handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			actualSession := supertokens.GetSessionFromRequest(r)
 			if actualSession != nil {
 				session := Session{
@@ -45,12 +76,236 @@ func Middleware(condition ...bool) func(*gin.Context) {
 				c.Set(sessionContext, &session)
 			}
 			c.Next()
-		}, params...)
-		handler(c.Writer, c.Request)
+		}))
+		handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// RequireClaims returns a middleware that responds with a ForbiddenError unless the JWT payload meets all
+// requirements. It has to run after the session middleware
+func RequireClaims(requirements ...supertokens.ClaimRequirement) func(*gin.Context) {
+	return func(c *gin.Context) {
+		session := GetSessionFromRequest(c)
+		if session == nil {
+			c.Abort()
+			HandleErrorAndRespond(errors.UnauthorizedError{
+				Msg: "no session found for this request",
+			}, c)
+			return
+		}
+		if err := supertokens.CheckClaims(*session.actualSession, requirements...); err != nil {
+			c.Abort()
+			HandleErrorAndRespond(err, c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRole returns a middleware that responds with a ForbiddenError unless the session has at least one of
+// roles. It has to run after the session middleware
+func RequireRole(roles ...string) func(*gin.Context) {
+	return RequireClaims(supertokens.ClaimIncludesAny(supertokens.GetRolesClaimPath(), roles...))
+}
+
+// RequireRecentAuth returns a middleware that responds with a ReauthenticationRequiredError unless the user
+// authenticated within maxAge, with at least minAssuranceLevel if given. It has to run after the session middleware
+func RequireRecentAuth(maxAge time.Duration, minAssuranceLevel ...int) func(*gin.Context) {
+	return func(c *gin.Context) {
+		session := GetSessionFromRequest(c)
+		if session == nil {
+			c.Abort()
+			HandleErrorAndRespond(errors.UnauthorizedError{
+				Msg: "no session found for this request",
+			}, c)
+			return
+		}
+		if err := supertokens.CheckRecentAuth(*session.actualSession, maxAge, minAssuranceLevel...); err != nil {
+			c.Abort()
+			HandleErrorAndRespond(err, c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// BlockImpersonation returns a middleware that responds with a ForbiddenError for impersonation sessions. It has
+// to run after the session middleware
+func BlockImpersonation() func(*gin.Context) {
+	return func(c *gin.Context) {
+		session := GetSessionFromRequest(c)
+		if session == nil {
+			c.Abort()
+			HandleErrorAndRespond(errors.UnauthorizedError{
+				Msg: "no session found for this request",
+			}, c)
+			return
+		}
+		if err := supertokens.CheckNotImpersonated(*session.actualSession, c.Request); err != nil {
+			c.Abort()
+			HandleErrorAndRespond(err, c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RegisterRoutes function used to add the refresh API and a sign out API at SignOutPath to r. The refresh API path
+// has to be within the base path of r
+func RegisterRoutes(r *gin.RouterGroup) error {
+	refreshAPIPath, err := supertokens.GetRefreshAPIPath()
+	if err != nil {
+		return err
+	}
+	basePath := strings.TrimSuffix(r.BasePath(), "/")
+	if !strings.HasPrefix(refreshAPIPath, basePath+"/") {
+		return errors.GeneralError{
+			Msg: "refresh API path " + refreshAPIPath + " is not within " + r.BasePath(),
+		}
 	}
+	r.POST(strings.TrimPrefix(refreshAPIPath, basePath), Middleware(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "OK"})
+	})
+	r.POST(SignOutPath, MiddlewareWithOptions(MiddlewareOptions{SessionOptional: true}), func(c *gin.Context) {
+		session := GetSessionFromRequest(c)
+		if session != nil {
+			if err := session.RevokeSession(); err != nil {
+				HandleErrorAndRespond(err, c)
+				return
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "OK"})
+	})
+	return nil
 }
 
 // HandleErrorAndRespond if error handlers are provided, then uses those, else does default error handling depending on the type of error
 func HandleErrorAndRespond(err error, c *gin.Context) {
-	supertokens.HandleErrorAndRespond(err, c.Writer)
+	if errors.IsUnauthorizedError(err) {
+		onUnauthorizedErrorHandler(err, c)
+	} else if errors.IsTryRefreshTokenError(err) {
+		onTryRefreshTokenErrorHandler(err, c)
+	} else if errors.IsTokenTheftDetectedError(err) {
+		actualError := err.(errors.TokenTheftDetectedError)
+		onTokenTheftDetectedErrorHandler(actualError.SessionHandle, actualError.UserID, c)
+	} else if errors.IsForbiddenError(err) {
+		onForbiddenErrorHandler(err, c)
+	} else if errors.IsInvalidClaimError(err) {
+		onInvalidClaimErrorHandler(err, c)
+	} else if errors.IsReauthenticationRequiredError(err) {
+		onReauthenticationRequiredHandler(err, c)
+	} else {
+		onGeneralErrorHandler(err, c)
+	}
+}
+
+// JSONErrorResponder responds to err with a JSON body that has the error type and message, using the status codes
+// of the default error handlers. It can be used as OnError of MiddlewareOptions
+func JSONErrorResponder(err error, c *gin.Context) {
+	errorType := "GENERAL_ERROR"
+	if errors.IsUnauthorizedError(err) {
+		errorType = "UNAUTHORISED"
+	} else if errors.IsTryRefreshTokenError(err) {
+		errorType = "TRY_REFRESH_TOKEN"
+	} else if errors.IsTokenTheftDetectedError(err) {
+		errorType = "TOKEN_THEFT_DETECTED"
+		theftError := err.(errors.TokenTheftDetectedError)
+		supertokens.RevokeSessionAfterTokenTheft(theftError.SessionHandle, theftError.UserID)
+	}
+	status := http.StatusInternalServerError
+	body := gin.H{}
+	if errors.IsForbiddenError(err) {
+		errorType = "FORBIDDEN"
+		status = http.StatusForbidden
+	} else if errors.IsInvalidClaimError(err) {
+		errorType = "INVALID_CLAIM"
+		status = http.StatusForbidden
+		body["claimKey"] = err.(errors.InvalidClaimError).ClaimKey
+	} else if errors.IsReauthenticationRequiredError(err) {
+		errorType = "REAUTHENTICATION_REQUIRED"
+		status = http.StatusForbidden
+	} else if errorType != "GENERAL_ERROR" {
+		handshakeInfo, handshakeInfoError := core.GetHandshakeInfoInstance()
+		if handshakeInfoError != nil {
+			JSONErrorResponder(handshakeInfoError, c)
+			return
+		}
+		status = handshakeInfo.SessionExpiredStatusCode
+	}
+	body["type"] = errorType
+	body["message"] = err.Error()
+	c.AbortWithStatusJSON(status, body)
+}
+
+var onTokenTheftDetectedErrorHandler = defaultTokenTheftDetectedErrorHandler
+var onUnauthorizedErrorHandler = defaultUnauthorizedErrorHandler
+var onTryRefreshTokenErrorHandler = defaultTryRefreshTokenErrorHandler
+var onGeneralErrorHandler = defaultGeneralErrorHandler
+var onForbiddenErrorHandler = defaultForbiddenErrorHandler
+var onInvalidClaimErrorHandler = defaultInvalidClaimErrorHandler
+var onReauthenticationRequiredHandler = defaultReauthenticationRequiredHandler
+
+// OnTokenTheftDetected function to override default behaviour of handling token thefts
+func OnTokenTheftDetected(handler func(sessionHandle string, userID string, c *gin.Context)) {
+	onTokenTheftDetectedErrorHandler = handler
+}
+
+// OnUnauthorized function to override default behaviour of handling Unauthorized error
+func OnUnauthorized(handler func(error, *gin.Context)) {
+	onUnauthorizedErrorHandler = handler
+}
+
+// OnTryRefreshToken function to override default behaviour of handling try refresh token errors
+func OnTryRefreshToken(handler func(error, *gin.Context)) {
+	onTryRefreshTokenErrorHandler = handler
+}
+
+// OnGeneralError function to override default behaviour of handling general errors
+func OnGeneralError(handler func(error, *gin.Context)) {
+	onGeneralErrorHandler = handler
+}
+
+// OnForbidden function to override default behaviour of handling forbidden errors
+func OnForbidden(handler func(error, *gin.Context)) {
+	onForbiddenErrorHandler = handler
+}
+
+// OnInvalidClaim function to override default behaviour of handling invalid claim errors
+func OnInvalidClaim(handler func(error, *gin.Context)) {
+	onInvalidClaimErrorHandler = handler
+}
+
+// OnReauthenticationRequired function to override default behaviour of handling reauthentication required errors
+func OnReauthenticationRequired(handler func(error, *gin.Context)) {
+	onReauthenticationRequiredHandler = handler
+}
+
+// the default handlers use the handlers of the supertokens package, so that those can still be overridden
+
+func defaultTokenTheftDetectedErrorHandler(sessionHandle string, userID string, c *gin.Context) {
+	core.GetErrorHandlersInstance().OnTokenTheftDetectedErrorHandler(sessionHandle, userID, c.Writer)
+}
+
+func defaultUnauthorizedErrorHandler(err error, c *gin.Context) {
+	core.GetErrorHandlersInstance().OnUnauthorizedErrorHandler(err, c.Writer)
+}
+
+func defaultTryRefreshTokenErrorHandler(err error, c *gin.Context) {
+	core.GetErrorHandlersInstance().OnTryRefreshTokenErrorHandler(err, c.Writer)
+}
+
+func defaultGeneralErrorHandler(err error, c *gin.Context) {
+	core.GetErrorHandlersInstance().OnGeneralErrorHandler(err, c.Writer)
+}
+
+func defaultForbiddenErrorHandler(err error, c *gin.Context) {
+	core.GetErrorHandlersInstance().OnForbiddenErrorHandler(err, c.Writer)
+}
+
+func defaultInvalidClaimErrorHandler(err error, c *gin.Context) {
+	core.GetErrorHandlersInstance().OnInvalidClaimErrorHandler(err, c.Writer)
+}
+
+func defaultReauthenticationRequiredHandler(err error, c *gin.Context) {
+	core.GetErrorHandlersInstance().OnReauthenticationRequiredHandler(err, c.Writer)
 }
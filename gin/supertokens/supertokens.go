@@ -17,43 +17,57 @@
 package supertokens
 
 import (
-	"net/http"
+	"io"
+	"log"
 
 	"github.com/gin-gonic/gin"
 	"github.com/supertokens/supertokens-go/supertokens"
+	"github.com/supertokens/supertokens-go/supertokens/core"
 )
 
 // SessionContext string to get session struct from context if using Gin
 const sessionContext string = "supertokens_session_key"
 
 // ConfigMap add key value params for session behaviour
-type ConfigMap struct {
-	Hosts           string
-	AccessTokenPath string
-	RefreshAPIPath  string
-	CookieDomain    string
-	CookieSecure    *bool
-	CookieSameSite  string
-	APIKey          string
-}
+type ConfigMap = supertokens.ConfigMap
 
 // Config used to set locations of SuperTokens instances
 func Config(config ConfigMap) {
-	supertokens.Config(supertokens.ConfigMap{
-		Hosts:           config.Hosts,
-		AccessTokenPath: config.AccessTokenPath,
-		RefreshAPIPath:  config.RefreshAPIPath,
-		CookieDomain:    config.CookieDomain,
-		CookieSecure:    config.CookieSecure,
-		CookieSameSite:  config.CookieSameSite,
-		APIKey:          config.APIKey,
-	})
+	supertokens.Config(config)
+}
+
+// NewStandardLogger returns a Logger that writes debug events to a *log.Logger
+func NewStandardLogger(logger *log.Logger) core.Logger {
+	return supertokens.NewStandardLogger(logger)
+}
+
+// NewFileHandshakeStore returns a HandshakeStore that keeps the handshake info in a file at path
+func NewFileHandshakeStore(path string) core.HandshakeStore {
+	return supertokens.NewFileHandshakeStore(path)
+}
+
+// NewInMemoryHandshakeStore returns a HandshakeStore that keeps the handshake info for the lifetime of the process
+func NewInMemoryHandshakeStore() core.HandshakeStore {
+	return supertokens.NewInMemoryHandshakeStore()
 }
 
 // CreateNewSession function used to create a new SuperTokens session
 func CreateNewSession(c *gin.Context, userID string,
 	payload ...map[string]interface{}) (Session, error) {
-	actualSession, err := supertokens.CreateNewSession(c.Writer, userID, payload...)
+	actualSession, err := supertokens.CreateNewSessionWithRequest(c.Writer, c.Request, userID, payload...)
+	if err != nil {
+		return Session{}, err
+	}
+	return Session{
+		actualSession: &actualSession,
+	}, nil
+}
+
+// CreateImpersonationSession function used to create a session for targetUserID that is used by actorUserID
+func CreateImpersonationSession(c *gin.Context, targetUserID string, actorUserID string,
+	payload ...map[string]interface{}) (Session, error) {
+	actualSession, err := supertokens.CreateImpersonationSessionWithRequest(c.Writer, c.Request, targetUserID, actorUserID,
+		payload...)
 	if err != nil {
 		return Session{}, err
 	}
@@ -84,6 +98,29 @@ func RefreshSession(c *gin.Context) (Session, error) {
 	}, nil
 }
 
+// GetSessionForWebSocket function used to verify the session of a WebSocket upgrade request. There is no anti-csrf
+// check, so the Origin header is checked against WebSocketAllowedOrigins instead
+func GetSessionForWebSocket(c *gin.Context) (Session, error) {
+	actualSession, err := supertokens.GetSessionForWebSocket(c.Writer, c.Request)
+	if err != nil {
+		return Session{}, err
+	}
+	return Session{
+		actualSession: &actualSession,
+	}, nil
+}
+
+// TrackConnection function used to close connection when its session is revoked in this process or its access
+// token expires. The returned function stops tracking and has to be called once the connection is closed
+func TrackConnection(session Session, connection io.Closer) func() {
+	return supertokens.TrackConnection(*session.actualSession, connection)
+}
+
+// GetTrackedConnectionCount function used to get the number of open connections tracked for a session
+func GetTrackedConnectionCount(sessionHandle string) int {
+	return supertokens.GetTrackedConnectionCount(sessionHandle)
+}
+
 // RevokeAllSessionsForUser function used to revoke all sessions for a user
 func RevokeAllSessionsForUser(userID string) ([]string, error) {
 	return supertokens.RevokeAllSessionsForUser(userID)
@@ -94,6 +131,26 @@ func GetAllSessionHandlesForUser(userID string) ([]string, error) {
 	return supertokens.GetAllSessionHandlesForUser(userID)
 }
 
+// RevokeSessionsForUsers function used to revoke all sessions of many users
+func RevokeSessionsForUsers(userIDs []string) (map[string][]string, error) {
+	return supertokens.RevokeSessionsForUsers(userIDs)
+}
+
+// GetDeviceMetadataFromSessionData function used to read device metadata from the session data of a session
+func GetDeviceMetadataFromSessionData(sessionData map[string]interface{}) *supertokens.DeviceMetadata {
+	return supertokens.GetDeviceMetadataFromSessionData(sessionData)
+}
+
+// GetAllSessionsForUser function used to get information about all sessions of a user
+func GetAllSessionsForUser(userID string) ([]core.SessionInformation, error) {
+	return supertokens.GetAllSessionsForUser(userID)
+}
+
+// GetSessionInformation function used to get the user ID, session data, jwt payload and lifetime of a session
+func GetSessionInformation(sessionHandle string) (core.SessionInformation, error) {
+	return supertokens.GetSessionInformation(sessionHandle)
+}
+
 // RevokeSession function used to revoke a specific session
 func RevokeSession(sessionHandle string) (bool, error) {
 	return supertokens.RevokeSession(sessionHandle)
@@ -134,24 +191,49 @@ func UpdateJWTPayload(sessionHandle string, newJWTPayload map[string]interface{}
 	return supertokens.UpdateJWTPayload(sessionHandle, newJWTPayload)
 }
 
-// OnTokenTheftDetected function to override default behaviour of handling token thefts
-func OnTokenTheftDetected(handler func(string, string, http.ResponseWriter)) {
-	supertokens.OnTokenTheftDetected(handler)
+// OnImpersonation function to get notified when an impersonation session is created, expires or is blocked
+func OnImpersonation(hook func(core.ImpersonationEvent)) {
+	supertokens.OnImpersonation(hook)
+}
+
+// OnSessionCreated function to get notified after a new session has been created
+func OnSessionCreated(hook func(core.SessionEvent)) {
+	supertokens.OnSessionCreated(hook)
+}
+
+// OnSessionRefreshed function to get notified after a session has been refreshed
+func OnSessionRefreshed(hook func(core.SessionEvent)) {
+	supertokens.OnSessionRefreshed(hook)
 }
 
-// OnUnauthorized function to override default behaviour of handling Unauthorized error
-func OnUnauthorized(handler func(error, http.ResponseWriter)) {
-	supertokens.OnUnauthorized(handler)
+// OnSessionRevoked function to get notified after a session has been revoked
+func OnSessionRevoked(hook func(core.SessionEvent)) {
+	supertokens.OnSessionRevoked(hook)
 }
 
-// OnTryRefreshToken function to override default behaviour of handling try refresh token errors
-func OnTryRefreshToken(handler func(error, http.ResponseWriter)) {
-	supertokens.OnTryRefreshToken(handler)
+// OnJWTPayloadUpdated function to get notified after the jwt payload of a session has been updated
+func OnJWTPayloadUpdated(hook func(core.SessionEvent)) {
+	supertokens.OnJWTPayloadUpdated(hook)
 }
 
-// OnGeneralError function to override default behaviour of handling general errors
-func OnGeneralError(handler func(error, http.ResponseWriter)) {
-	supertokens.OnGeneralError(handler)
+// OnDegradedModeChange function to get notified when sessions start or stop being verified without the core
+func OnDegradedModeChange(handler func(active bool, err error)) {
+	supertokens.OnDegradedModeChange(handler)
+}
+
+// GetDegradedModeStats function used to get counters about sessions verified without the core
+func GetDegradedModeStats() core.DegradedModeStats {
+	return supertokens.GetDegradedModeStats()
+}
+
+// MustGetSession returns the verified session object. It panics if there is none, which only happens on routes
+// without the middleware or with optional sessions
+func MustGetSession(c *gin.Context) *Session {
+	session := GetSessionFromRequest(c)
+	if session == nil {
+		panic("no session in gin context. Is the route protected by the supertokens middleware?")
+	}
+	return session
 }
 
 // GetSessionFromRequest returns the verified session object if present, otherwise returns nil
@@ -0,0 +1,606 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/supertokens/supertokens-go/internal/fakecore"
+	"github.com/supertokens/supertokens-go/supertokens"
+	"github.com/supertokens/supertokens-go/supertokens/core"
+)
+
+func startFakeCore() *httptest.Server {
+	server, _ := fakecore.Start()
+	onTokenTheftDetectedErrorHandler = defaultTokenTheftDetectedErrorHandler
+	onUnauthorizedErrorHandler = defaultUnauthorizedErrorHandler
+	onTryRefreshTokenErrorHandler = defaultTryRefreshTokenErrorHandler
+	onGeneralErrorHandler = defaultGeneralErrorHandler
+	onForbiddenErrorHandler = defaultForbiddenErrorHandler
+	onInvalidClaimErrorHandler = defaultInvalidClaimErrorHandler
+	onReauthenticationRequiredHandler = defaultReauthenticationRequiredHandler
+	Config(ConfigMap{
+		Hosts: server.URL,
+	})
+	return server
+}
+
+func newTestServer() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/login", func(c *gin.Context) {
+		payload := map[string]interface{}{"roles": c.QueryArray("role")}
+		if _, err := CreateNewSession(c, c.Query("userId"), payload); err != nil {
+			HandleErrorAndRespond(err, c)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	r.GET("/user", Middleware(), func(c *gin.Context) {
+		c.String(http.StatusOK, MustGetSession(c).GetUserID())
+	})
+	r.GET("/optional", MiddlewareWithOptions(MiddlewareOptions{SessionOptional: true}), func(c *gin.Context) {
+		session := GetSessionFromRequest(c)
+		if session == nil {
+			c.String(http.StatusOK, "anonymous")
+			return
+		}
+		c.String(http.StatusOK, session.GetUserID())
+	})
+	r.GET("/json", MiddlewareWithOptions(MiddlewareOptions{OnError: JSONErrorResponder}), func(c *gin.Context) {
+		c.String(http.StatusOK, MustGetSession(c).GetUserID())
+	})
+	r.GET("/admin", Middleware(), RequireRole("admin"), func(c *gin.Context) {
+		c.String(http.StatusOK, "admin")
+	})
+	if err := RegisterRoutes(&r.RouterGroup); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func serve(r *gin.Engine, method string, path string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, nil)
+	for _, cookie := range cookies {
+		request.AddCookie(cookie)
+	}
+	response := httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	return response
+}
+
+func TestMiddlewareVerifiesAndRefreshesSession(t *testing.T) {
+	defer startFakeCore().Close()
+	r := newTestServer()
+
+	login := serve(r, "POST", "/login?userId=user1", nil)
+	if login.Code != http.StatusOK {
+		t.Fatal("login failed", login.Code, login.Body.String())
+	}
+	cookies := login.Result().Cookies()
+
+	user := serve(r, "GET", "/user", cookies)
+	if user.Code != http.StatusOK || user.Body.String() != "user1" {
+		t.Error("session was not verified", user.Code, user.Body.String())
+	}
+
+	refresh := serve(r, "POST", "/refresh", cookies)
+	if refresh.Code != http.StatusOK {
+		t.Error("session was not refreshed", refresh.Code, refresh.Body.String())
+	}
+	refreshedCookies := refresh.Result().Cookies()
+	if len(refreshedCookies) == 0 || refreshedCookies[0].Value == cookies[0].Value {
+		t.Error("new tokens were not set", refreshedCookies)
+	}
+}
+
+func TestOptionalSessionsAndJSONErrors(t *testing.T) {
+	defer startFakeCore().Close()
+	r := newTestServer()
+
+	anonymous := serve(r, "GET", "/optional", nil)
+	if anonymous.Code != http.StatusOK || anonymous.Body.String() != "anonymous" {
+		t.Error("optional session was not let through", anonymous.Code, anonymous.Body.String())
+	}
+	if setCookies := anonymous.Result().Cookies(); len(setCookies) != 0 {
+		t.Error("cookies of an anonymous request were cleared", setCookies)
+	}
+
+	login := serve(r, "POST", "/login?userId=user1", nil)
+	cookies := login.Result().Cookies()
+	withSession := serve(r, "GET", "/optional", cookies)
+	if withSession.Body.String() != "user1" {
+		t.Error("optional session was not verified", withSession.Body.String())
+	}
+
+	missingSession := serve(r, "GET", "/json", nil)
+	var body map[string]interface{}
+	json.NewDecoder(missingSession.Body).Decode(&body)
+	if missingSession.Code != 401 || body["type"] != "UNAUTHORISED" || body["message"] != "idRefreshToken missing" {
+		t.Error("incorrect JSON error", missingSession.Code, body)
+	}
+
+	OnUnauthorized(func(err error, c *gin.Context) {
+		c.AbortWithStatus(http.StatusForbidden)
+	})
+	custom := serve(r, "GET", "/user", nil)
+	if custom.Code != http.StatusForbidden {
+		t.Error("custom error handler was not used", custom.Code)
+	}
+}
+
+func TestSignOut(t *testing.T) {
+	defer startFakeCore().Close()
+	r := newTestServer()
+
+	signOut := serve(r, "POST", SignOutPath, nil)
+	if signOut.Code != http.StatusOK {
+		t.Error("sign out without a session failed", signOut.Code, signOut.Body.String())
+	}
+
+	login := serve(r, "POST", "/login?userId=user1", nil)
+	signOut = serve(r, "POST", SignOutPath, login.Result().Cookies())
+	if signOut.Code != http.StatusOK {
+		t.Error("sign out failed", signOut.Code, signOut.Body.String())
+	}
+	for _, cookie := range signOut.Result().Cookies() {
+		if cookie.Value != "" {
+			t.Error("cookie was not cleared", cookie)
+		}
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	defer startFakeCore().Close()
+	r := newTestServer()
+
+	admin := serve(r, "POST", "/login?userId=user1&role=admin&role=support", nil).Result().Cookies()
+	support := serve(r, "POST", "/login?userId=user2&role=support", nil).Result().Cookies()
+
+	allowed := serve(r, "GET", "/admin", admin)
+	if allowed.Code != http.StatusOK || allowed.Body.String() != "admin" {
+		t.Error("admin was not let through", allowed.Code, allowed.Body.String())
+	}
+	forbidden := serve(r, "GET", "/admin", support)
+	if forbidden.Code != http.StatusForbidden || forbidden.Body.String() == "admin" {
+		t.Error("forbidden error was not returned", forbidden.Code, forbidden.Body.String())
+	}
+
+	var body map[string]interface{}
+	OnForbidden(JSONErrorResponder)
+	forbidden = serve(r, "GET", "/admin", support)
+	json.NewDecoder(forbidden.Body).Decode(&body)
+	if forbidden.Code != http.StatusForbidden || body["type"] != "FORBIDDEN" {
+		t.Error("incorrect JSON error", forbidden.Code, body)
+	}
+
+	OnForbidden(func(err error, c *gin.Context) {
+		c.String(http.StatusTeapot, "custom")
+	})
+	custom := serve(r, "GET", "/admin", support)
+	if custom.Code != http.StatusTeapot || custom.Body.String() != "custom" {
+		t.Error("custom forbidden handler was not used", custom.Code, custom.Body.String())
+	}
+}
+
+func TestSessionClaims(t *testing.T) {
+	server := startFakeCore()
+	defer server.Close()
+	var lock sync.Mutex
+	emailVerified := false
+	fetchCount := 0
+	Config(ConfigMap{
+		Hosts: server.URL,
+		SessionClaims: []supertokens.SessionClaim{{
+			Key: "emailVerified",
+			Fetch: func(ctx context.Context, userID string) (interface{}, error) {
+				lock.Lock()
+				defer lock.Unlock()
+				fetchCount++
+				return emailVerified, nil
+			},
+			MaxAge: 50 * time.Millisecond,
+			Validate: func(value interface{}) error {
+				if value != true {
+					return stderrors.New("email is not verified")
+				}
+				return nil
+			},
+		}},
+	})
+	r := newTestServer()
+	r.POST("/verify-email", MiddlewareWithOptions(MiddlewareOptions{SkipClaimValidation: true}), func(c *gin.Context) {
+		lock.Lock()
+		defer lock.Unlock()
+		emailVerified = true
+		c.Status(http.StatusOK)
+	})
+
+	cookies := serve(r, "POST", "/login?userId=user1", nil).Result().Cookies()
+	var body map[string]interface{}
+	invalid := serve(r, "GET", "/json", cookies)
+	json.NewDecoder(invalid.Body).Decode(&body)
+	if invalid.Code != http.StatusForbidden || body["type"] != "INVALID_CLAIM" || body["claimKey"] != "emailVerified" {
+		t.Error("invalid claim error was not returned", invalid.Code, body)
+	}
+
+	if verify := serve(r, "POST", "/verify-email", cookies); verify.Code != http.StatusOK {
+		t.Error("claim validation was not skipped", verify.Code, verify.Body.String())
+	}
+	// the fetched value is used until it is stale
+	if user := serve(r, "GET", "/user", cookies); user.Code != http.StatusForbidden {
+		t.Error("stale claim was refetched too early", user.Code)
+	}
+	time.Sleep(100 * time.Millisecond)
+	user := serve(r, "GET", "/user", cookies)
+	if user.Code != http.StatusOK || user.Body.String() != "user1" {
+		t.Error("stale claim was not refetched", user.Code, user.Body.String())
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if fetchCount != 2 {
+		t.Error("incorrect number of fetches", fetchCount)
+	}
+}
+
+func TestNestedSessionClaim(t *testing.T) {
+	server := startFakeCore()
+	defer server.Close()
+	Config(ConfigMap{
+		Hosts: server.URL,
+		SessionClaims: []supertokens.SessionClaim{{
+			Key: "user.emailVerified",
+			Fetch: func(ctx context.Context, userID string) (interface{}, error) {
+				return true, nil
+			},
+			Validate: func(value interface{}) error {
+				if value != true {
+					return stderrors.New("email is not verified")
+				}
+				return nil
+			},
+		}},
+	})
+	r := newTestServer()
+	r.GET("/payload", Middleware(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, MustGetSession(c).GetJWTPayload())
+	})
+
+	cookies := serve(r, "POST", "/login?userId=user1", nil).Result().Cookies()
+	response := serve(r, "GET", "/payload", cookies)
+	var payload map[string]interface{}
+	json.NewDecoder(response.Body).Decode(&payload)
+	if response.Code != http.StatusOK {
+		t.Fatal("nested claim was not validated", response.Code, response.Body.String())
+	}
+	if value, _ := supertokens.GetClaim(payload, "user.emailVerified"); value != true {
+		t.Error("nested claim was not saved in the payload", payload)
+	}
+	if _, ok := payload["user.emailVerified"]; ok {
+		t.Error("nested claim was saved under its dotted key", payload)
+	}
+}
+
+func TestRequireRecentAuth(t *testing.T) {
+	defer startFakeCore().Close()
+	r := newTestServer()
+	r.GET("/billing", Middleware(), RequireRecentAuth(time.Hour, 2), func(c *gin.Context) {
+		c.String(http.StatusOK, "billing")
+	})
+	r.GET("/password", Middleware(), RequireRecentAuth(time.Nanosecond), func(c *gin.Context) {
+		c.String(http.StatusOK, "password")
+	})
+	r.POST("/second-factor", Middleware(), func(c *gin.Context) {
+		if err := MustGetSession(c).MarkReauthenticated(2); err != nil {
+			HandleErrorAndRespond(err, c)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	cookies := serve(r, "POST", "/login?userId=user1", nil).Result().Cookies()
+	OnReauthenticationRequired(JSONErrorResponder)
+	var body map[string]interface{}
+	lowLevel := serve(r, "GET", "/billing", cookies)
+	json.NewDecoder(lowLevel.Body).Decode(&body)
+	if lowLevel.Code != http.StatusForbidden || body["type"] != "REAUTHENTICATION_REQUIRED" {
+		t.Error("reauthentication required error was not returned", lowLevel.Code, body)
+	}
+	if stale := serve(r, "GET", "/password", cookies); stale.Code != http.StatusForbidden {
+		t.Error("stale authentication was accepted", stale.Code)
+	}
+
+	if secondFactor := serve(r, "POST", "/second-factor", cookies); secondFactor.Code != http.StatusOK {
+		t.Fatal("marking the session as reauthenticated failed", secondFactor.Code, secondFactor.Body.String())
+	}
+	billing := serve(r, "GET", "/billing", cookies)
+	if billing.Code != http.StatusOK || billing.Body.String() != "billing" {
+		t.Error("reauthenticated session was not let through", billing.Code, billing.Body.String())
+	}
+}
+
+func TestAuthenticationIsRecordedOnlyIfEnabled(t *testing.T) {
+	server := startFakeCore()
+	defer server.Close()
+	r := newTestServer()
+	var payloads []map[string]interface{}
+	r.POST("/create", func(c *gin.Context) {
+		payload := map[string]interface{}{}
+		if c.Query("level") != "" {
+			payload = supertokens.WithAssuranceLevel(payload, 2)
+		}
+		session, err := CreateNewSession(c, "user1", payload)
+		if err != nil {
+			HandleErrorAndRespond(err, c)
+			return
+		}
+		payloads = append(payloads, session.GetJWTPayload())
+	})
+
+	serve(r, "POST", "/create", nil)
+	serve(r, "POST", "/create?level=2", nil)
+	Config(ConfigMap{Hosts: server.URL, RecordAuthentication: true})
+	serve(r, "POST", "/create", nil)
+	if len(payloads) != 3 {
+		t.Fatal("sessions were not created", payloads)
+	}
+	if _, ok := payloads[0][supertokens.AuthTimeKey]; ok || len(payloads[0]) != 0 {
+		t.Error("authentication was recorded without being enabled", payloads[0])
+	}
+	if _, ok := payloads[1][supertokens.AuthTimeKey]; !ok || payloads[1][supertokens.AssuranceLevelKey] != float64(2) {
+		t.Error("authentication was not recorded with an assurance level", payloads[1])
+	}
+	if _, ok := payloads[2][supertokens.AuthTimeKey]; !ok ||
+		payloads[2][supertokens.AssuranceLevelKey] != float64(supertokens.DefaultAssuranceLevel) {
+		t.Error("authentication was not recorded", payloads[2])
+	}
+}
+
+func TestImpersonation(t *testing.T) {
+	server := startFakeCore()
+	defer server.Close()
+	Config(ConfigMap{
+		Hosts:                    server.URL,
+		ImpersonationMaxLifetime: 100 * time.Millisecond,
+	})
+	var events []core.ImpersonationEvent
+	OnImpersonation(func(event core.ImpersonationEvent) {
+		events = append(events, event)
+	})
+	defer core.ResetSessionHooks()
+	r := newTestServer()
+	r.POST("/impersonate", func(c *gin.Context) {
+		if _, err := CreateImpersonationSession(c, c.Query("userId"), "support1"); err != nil {
+			HandleErrorAndRespond(err, c)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	r.GET("/actor", Middleware(), func(c *gin.Context) {
+		session := MustGetSession(c)
+		if !session.IsImpersonated() {
+			c.String(http.StatusOK, "")
+			return
+		}
+		c.String(http.StatusOK, session.GetActorID())
+	})
+	r.POST("/password", Middleware(), BlockImpersonation(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	r.GET("/billing", Middleware(), RequireRecentAuth(time.Hour), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	r.POST("/fake-impersonation", func(c *gin.Context) {
+		_, err := CreateNewSession(c, "user1", map[string]interface{}{supertokens.ActorIDKey: "support1"})
+		if err == nil {
+			c.Status(http.StatusOK)
+			return
+		}
+		HandleErrorAndRespond(err, c)
+	})
+
+	if fake := serve(r, "POST", "/fake-impersonation", nil); fake.Code != http.StatusInternalServerError {
+		t.Error("reserved impersonation key was accepted by CreateNewSession", fake.Code)
+	}
+	userCookies := serve(r, "POST", "/login?userId=user1", nil).Result().Cookies()
+	if actor := serve(r, "GET", "/actor", userCookies); actor.Body.String() != "" {
+		t.Error("normal session is impersonated", actor.Body.String())
+	}
+	if password := serve(r, "POST", "/password", userCookies); password.Code != http.StatusOK {
+		t.Error("normal session was blocked", password.Code)
+	}
+
+	cookies := serve(r, "POST", "/impersonate?userId=user1", nil).Result().Cookies()
+	if actor := serve(r, "GET", "/actor", cookies); actor.Body.String() != "support1" {
+		t.Error("incorrect actor", actor.Code, actor.Body.String())
+	}
+	if password := serve(r, "POST", "/password", cookies); password.Code != http.StatusForbidden {
+		t.Error("impersonation session was not blocked", password.Code)
+	}
+	if billing := serve(r, "GET", "/billing", cookies); billing.Code != http.StatusForbidden {
+		t.Error("impersonation session passed the recent authentication check", billing.Code)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if expired := serve(r, "GET", "/actor", cookies); expired.Code != 401 {
+		t.Error("expired impersonation session was accepted", expired.Code, expired.Body.String())
+	}
+	if len(events) != 3 || events[0].Type != core.ImpersonationStarted || events[1].Type != core.ImpersonationBlocked ||
+		events[2].Type != core.ImpersonationExpired || events[2].ActorID != "support1" || events[2].UserID != "user1" {
+		t.Error("incorrect impersonation events", events)
+	}
+}
+
+func TestImpersonationKeysAreKept(t *testing.T) {
+	defer startFakeCore().Close()
+	r := newTestServer()
+	r.POST("/impersonate", func(c *gin.Context) {
+		if _, err := CreateImpersonationSession(c, c.Query("userId"), "support1"); err != nil {
+			HandleErrorAndRespond(err, c)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	r.POST("/update", Middleware(), func(c *gin.Context) {
+		session := MustGetSession(c)
+		err := session.MergeJWTPayload(map[string]interface{}{supertokens.ActorIDKey: nil, supertokens.ImpersonationExpiryKey: nil})
+		if err == nil {
+			err = session.UpdateJWTPayload(map[string]interface{}{"roles": []string{"admin"}})
+		}
+		if err == nil {
+			err = UpdateJWTPayload(session.GetHandle(), map[string]interface{}{supertokens.ActorIDKey: ""})
+		}
+		if err != nil {
+			HandleErrorAndRespond(err, c)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	r.GET("/actor", Middleware(), func(c *gin.Context) {
+		c.String(http.StatusOK, MustGetSession(c).GetActorID())
+	})
+
+	cookies := serve(r, "POST", "/impersonate?userId=user1", nil).Result().Cookies()
+	if update := serve(r, "POST", "/update", cookies); update.Code != http.StatusOK {
+		t.Fatal("updating the payload failed", update.Code, update.Body.String())
+	}
+	if actor := serve(r, "GET", "/actor", cookies); actor.Body.String() != "support1" {
+		t.Error("impersonation was removed by updating the payload", actor.Code, actor.Body.String())
+	}
+
+	userCookies := serve(r, "POST", "/login?userId=user2", nil).Result().Cookies()
+	r.POST("/become", Middleware(), func(c *gin.Context) {
+		err := MustGetSession(c).MergeJWTPayload(map[string]interface{}{supertokens.ActorIDKey: "support1"})
+		if err != nil {
+			HandleErrorAndRespond(err, c)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	serve(r, "POST", "/become", userCookies)
+	if actor := serve(r, "GET", "/actor", userCookies); actor.Body.String() != "" {
+		t.Error("normal session became an impersonation session", actor.Body.String())
+	}
+}
+
+func TestImpersonationWithoutValidExpiryIsRevoked(t *testing.T) {
+	server := startFakeCore()
+	defer server.Close()
+	fake := server.Config.Handler.(*fakecore.Core)
+	r := newTestServer()
+	r.POST("/impersonate", func(c *gin.Context) {
+		if _, err := CreateImpersonationSession(c, c.Query("userId"), "support1"); err != nil {
+			HandleErrorAndRespond(err, c)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	r.GET("/actor", Middleware(), func(c *gin.Context) {
+		c.String(http.StatusOK, MustGetSession(c).GetActorID())
+	})
+
+	for _, expiry := range []interface{}{nil, "never", -1} {
+		cookies := serve(r, "POST", "/impersonate?userId=user1", nil).Result().Cookies()
+		fake.ModifyJWTPayload("user1", func(payload map[string]interface{}) {
+			delete(payload, supertokens.ImpersonationExpiryKey)
+			if expiry != nil {
+				payload[supertokens.ImpersonationExpiryKey] = expiry
+			}
+		})
+		if actor := serve(r, "GET", "/actor", cookies); actor.Code != 401 {
+			t.Error("impersonation session without a valid expiry was accepted", expiry, actor.Code)
+		}
+	}
+}
+
+func TestTokenTheftRevokesThroughHooks(t *testing.T) {
+	defer startFakeCore().Close()
+	var revoked []core.SessionEvent
+	OnSessionRevoked(func(event core.SessionEvent) {
+		revoked = append(revoked, event)
+	})
+	defer core.ResetSessionHooks()
+	r := newTestServer()
+
+	cookies := serve(r, "POST", "/login?userId=user1", nil).Result().Cookies()
+	if refresh := serve(r, "POST", "/refresh", cookies); refresh.Code != http.StatusOK {
+		t.Fatal("session was not refreshed", refresh.Code, refresh.Body.String())
+	}
+	if theft := serve(r, "POST", "/refresh", cookies); theft.Code != 401 {
+		t.Error("token theft was not reported", theft.Code, theft.Body.String())
+	}
+	if len(revoked) != 1 || revoked[0].SessionHandle != "handle-user1" || revoked[0].UserID != "user1" {
+		t.Error("session revoked hook was not called", revoked)
+	}
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	server := startFakeCore()
+	defer server.Close()
+	r := newTestServer()
+	r.GET("/ws", func(c *gin.Context) {
+		session, err := GetSessionForWebSocket(c)
+		if err != nil {
+			HandleErrorAndRespond(err, c)
+			return
+		}
+		c.String(http.StatusOK, session.GetUserID())
+	})
+	cookies := serve(r, "POST", "/login?userId=user1", nil).Result().Cookies()
+	upgrade := func(origin string) int {
+		request := httptest.NewRequest("GET", "/ws", nil)
+		request.Host = "api.example.com"
+		if origin != "" {
+			request.Header.Set("Origin", origin)
+		}
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+		response := httptest.NewRecorder()
+		r.ServeHTTP(response, request)
+		return response.Code
+	}
+
+	if code := upgrade("https://api.example.com"); code != http.StatusOK {
+		t.Error("same origin was rejected", code)
+	}
+	if code := upgrade(""); code != http.StatusOK {
+		t.Error("request without origin was rejected", code)
+	}
+	if code := upgrade("https://evil.com"); code != http.StatusForbidden {
+		t.Error("cross origin request was accepted", code)
+	}
+
+	Config(ConfigMap{
+		Hosts:                   server.URL,
+		WebSocketAllowedOrigins: []string{"https://app.example.com/"},
+	})
+	if code := upgrade("https://app.example.com"); code != http.StatusOK {
+		t.Error("allowed origin was rejected", code)
+	}
+	if code := upgrade("https://api.example.com"); code != http.StatusForbidden {
+		t.Error("origin that is not allowed was accepted", code)
+	}
+}
@@ -317,18 +317,14 @@ func testError(c *gin.Context) {
 	response.Write([]byte("test error message"))
 }
 
-func customOnTryRefreshTokenError(err error, response http.ResponseWriter) {
-	response.WriteHeader(401)
-	response.Write([]byte(""))
-
+func customOnTryRefreshTokenError(err error, c *gin.Context) {
+	c.Status(401)
 }
 
-func customOnUnauthorizedError(err error, response http.ResponseWriter) {
-	response.WriteHeader(401)
-	response.Write([]byte(""))
+func customOnUnauthorizedError(err error, c *gin.Context) {
+	c.Status(401)
 }
 
-func customOnGeneralError(err error, response http.ResponseWriter) {
-	response.WriteHeader(http.StatusInternalServerError)
-	response.Write([]byte("Something went wrong"))
+func customOnGeneralError(err error, c *gin.Context) {
+	c.String(http.StatusInternalServerError, "Something went wrong")
 }
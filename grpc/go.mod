@@ -0,0 +1,20 @@
+module github.com/supertokens/supertokens-go/grpc
+
+// google.golang.org/grpc needs at least go 1.17, the other modules use go 1.13
+go 1.17
+
+require (
+	github.com/supertokens/supertokens-go v1.5.0
+	google.golang.org/genproto v0.0.0-20230410155749-daa745c078e1
+	google.golang.org/grpc v1.56.3
+)
+
+require (
+	github.com/golang/protobuf v1.5.3 // indirect
+	golang.org/x/net v0.9.0 // indirect
+	golang.org/x/sys v0.7.0 // indirect
+	golang.org/x/text v0.9.0 // indirect
+	google.golang.org/protobuf v1.30.0 // indirect
+)
+
+replace github.com/supertokens/supertokens-go => ../
@@ -0,0 +1,214 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"context"
+	"strings"
+
+	"github.com/supertokens/supertokens-go/supertokens"
+	"github.com/supertokens/supertokens-go/supertokens/core"
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// AuthorizationMetadataKey is the metadata key the access token is read from. A "Bearer " prefix is optional
+const AuthorizationMetadataKey = "authorization"
+
+// NewAccessTokenMetadataKey is the header metadata key a new access token from the core is sent in
+const NewAccessTokenMetadataKey = "st-access-token"
+
+// ErrorDomain is the domain of the ErrorInfo details of errors returned by the interceptors
+const ErrorDomain = "supertokens.io"
+
+// Reasons in the ErrorInfo details of codes.Unauthenticated errors returned by the interceptors
+const (
+	UnauthorisedReason    = "UNAUTHORISED"
+	TryRefreshTokenReason = "TRY_REFRESH_TOKEN"
+)
+
+// InvalidClaimReason is the reason in the ErrorInfo details of codes.PermissionDenied errors returned for
+// invalid session claims. The claim key is in its metadata under "claimKey"
+const InvalidClaimReason = "INVALID_CLAIM"
+
+type contextKey int
+
+const sessionContext contextKey = iota
+
+// InterceptorOptions add key value params for the interceptors
+type InterceptorOptions struct {
+	// AntiCsrfMetadataKey is the metadata key the anti-csrf token is read from. The anti-csrf check
+	// is only done if it is set
+	AntiCsrfMetadataKey string
+	// SessionOptional lets calls without an access token through. GetSessionFromContext returns nil for them
+	SessionOptional bool
+	// Skip returns true for the full method names that do not need a session, such as health checks
+	Skip func(fullMethod string) bool
+}
+
+// UnaryServerInterceptor returns an interceptor that verifies the access token in the metadata of unary calls
+func UnaryServerInterceptor(options ...InterceptorOptions) grpc.UnaryServerInterceptor {
+	actualOptions := getInterceptorOptions(options)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		if actualOptions.Skip != nil && actualOptions.Skip(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		ctx, err := verifySession(ctx, actualOptions, func(md metadata.MD) error {
+			return grpc.SetHeader(ctx, md)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns an interceptor that verifies the access token in the metadata of streams
+func StreamServerInterceptor(options ...InterceptorOptions) grpc.StreamServerInterceptor {
+	actualOptions := getInterceptorOptions(options)
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		if actualOptions.Skip != nil && actualOptions.Skip(info.FullMethod) {
+			return handler(srv, stream)
+		}
+		ctx, err := verifySession(stream.Context(), actualOptions, stream.SetHeader)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// GetSessionFromContext returns the verified session object if present, otherwise returns nil
+func GetSessionFromContext(ctx context.Context) *Session {
+	value := ctx.Value(sessionContext)
+	if value == nil {
+		return nil
+	}
+	return value.(*Session)
+}
+
+// GetErrorReason returns UnauthorisedReason, TryRefreshTokenReason or InvalidClaimReason for errors returned by
+// the interceptors, and "" for all other errors. It can be used by clients to decide whether to refresh the session
+func GetErrorReason(err error) string {
+	errorStatus, ok := status.FromError(err)
+	if !ok || (errorStatus.Code() != codes.Unauthenticated && errorStatus.Code() != codes.PermissionDenied) {
+		return ""
+	}
+	for _, detail := range errorStatus.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Domain == ErrorDomain {
+			return info.Reason
+		}
+	}
+	return ""
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream *serverStream) Context() context.Context {
+	return stream.ctx
+}
+
+func getInterceptorOptions(options []InterceptorOptions) InterceptorOptions {
+	if len(options) != 0 {
+		return options[0]
+	}
+	return InterceptorOptions{}
+}
+
+func verifySession(ctx context.Context, options InterceptorOptions,
+	setHeader func(metadata.MD) error) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	accessToken := getAccessTokenFromMetadata(md)
+	if accessToken == "" {
+		if options.SessionOptional {
+			return ctx, nil
+		}
+		return ctx, toStatusError(errors.UnauthorizedError{
+			Msg: "access token missing in metadata",
+		})
+	}
+	var antiCsrfToken *string
+	if options.AntiCsrfMetadataKey != "" {
+		if values := md.Get(options.AntiCsrfMetadataKey); len(values) != 0 {
+			antiCsrfToken = &values[0]
+		}
+	}
+	actualSession, err := supertokens.GetSessionFromAccessToken(ctx, accessToken, antiCsrfToken,
+		options.AntiCsrfMetadataKey != "")
+	if err != nil {
+		return ctx, toStatusError(err)
+	}
+	if actualSession.GetAccessToken() != accessToken {
+		if err := setHeader(metadata.Pairs(NewAccessTokenMetadataKey, actualSession.GetAccessToken())); err != nil {
+			core.LogDebug("sending new access token in header metadata failed", "error", err.Error())
+		}
+	}
+	session := Session{
+		actualSession: &actualSession,
+	}
+	return context.WithValue(ctx, sessionContext, &session), nil
+}
+
+func getAccessTokenFromMetadata(md metadata.MD) string {
+	values := md.Get(AuthorizationMetadataKey)
+	if len(values) == 0 {
+		return ""
+	}
+	value := strings.TrimSpace(values[0])
+	if len(value) > 7 && strings.EqualFold(value[:7], "bearer ") {
+		return strings.TrimSpace(value[7:])
+	}
+	return value
+}
+
+func toStatusError(err error) error {
+	var reason string
+	if errors.IsInvalidClaimError(err) {
+		errorStatus, detailsError := status.New(codes.PermissionDenied, err.Error()).WithDetails(&errdetails.ErrorInfo{
+			Reason:   InvalidClaimReason,
+			Domain:   ErrorDomain,
+			Metadata: map[string]string{"claimKey": err.(errors.InvalidClaimError).ClaimKey},
+		})
+		if detailsError != nil {
+			return status.Error(codes.PermissionDenied, err.Error())
+		}
+		return errorStatus.Err()
+	} else if errors.IsUnauthorizedError(err) {
+		reason = UnauthorisedReason
+	} else if errors.IsTryRefreshTokenError(err) {
+		reason = TryRefreshTokenReason
+	} else {
+		return status.Error(codes.Internal, err.Error())
+	}
+	errorStatus, detailsError := status.New(codes.Unauthenticated, err.Error()).WithDetails(&errdetails.ErrorInfo{
+		Reason: reason,
+		Domain: ErrorDomain,
+	})
+	if detailsError != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	return errorStatus.Err()
+}
@@ -0,0 +1,244 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/supertokens/supertokens-go/supertokens"
+	"github.com/supertokens/supertokens-go/supertokens/core"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// startFakeCore starts a core that accepts the access tokens "valid" and "rotate", asks to refresh "expired"
+// and rejects all other tokens. The signing key it hands out is always expired
+func startFakeCore() *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		var response map[string]interface{}
+		switch r.URL.Path {
+		case "/apiversion":
+			response = map[string]interface{}{"versions": []string{"2.3"}}
+		case "/handshake":
+			response = map[string]interface{}{
+				"status":                         "OK",
+				"jwtSigningPublicKey":            "key",
+				"jwtSigningPublicKeyExpiryTime":  0,
+				"cookieSecure":                   false,
+				"accessTokenPath":                "/",
+				"refreshTokenPath":               "/refresh",
+				"idRefreshTokenPath":             "/",
+				"enableAntiCsrf":                 true,
+				"accessTokenBlacklistingEnabled": false,
+				"cookieSameSite":                 "lax",
+				"sessionExpiredStatusCode":       401,
+			}
+		case "/session/verify":
+			response = map[string]interface{}{
+				"status":                        "OK",
+				"jwtSigningPublicKey":           "key",
+				"jwtSigningPublicKeyExpiryTime": 0,
+				"session": map[string]interface{}{
+					"handle":        "handle1",
+					"userId":        "user1",
+					"userDataInJWT": map[string]interface{}{},
+				},
+			}
+			switch body["accessToken"] {
+			case "valid":
+				if body["doAntiCsrfCheck"] == true && body["antiCsrfToken"] != "csrf" {
+					response = map[string]interface{}{"status": "TRY_REFRESH_TOKEN", "message": "anti-csrf check failed"}
+				}
+			case "rotate":
+				response["accessToken"] = map[string]interface{}{
+					"token":        "rotated",
+					"expiry":       4102444800000,
+					"createdTime":  0,
+					"cookiePath":   "/",
+					"cookieSecure": false,
+					"sameSite":     "lax",
+				}
+			case "expired":
+				response = map[string]interface{}{"status": "TRY_REFRESH_TOKEN", "message": "expired"}
+			default:
+				response = map[string]interface{}{"status": "UNAUTHORISED", "message": "unknown access token"}
+			}
+		default:
+			w.WriteHeader(404)
+			return
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	core.ResetQuerier()
+	core.ResetHandshakeInfo()
+	core.ResetVerifyCache()
+	supertokens.Config(supertokens.ConfigMap{
+		Hosts: server.URL,
+	})
+	return server
+}
+
+// healthServer reports SERVING only to calls with a session
+type healthServer struct {
+	*health.Server
+}
+
+func (server healthServer) Check(ctx context.Context,
+	request *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	if GetSessionFromContext(ctx) == nil {
+		return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_NOT_SERVING}, nil
+	}
+	return server.Server.Check(ctx, request)
+}
+
+func (server healthServer) Watch(request *grpc_health_v1.HealthCheckRequest,
+	stream grpc_health_v1.Health_WatchServer) error {
+	session := GetSessionFromContext(stream.Context())
+	if session == nil || session.GetUserID() != "user1" {
+		return status.Error(codes.Internal, "session missing")
+	}
+	return stream.Send(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING})
+}
+
+func startServer(t *testing.T, options InterceptorOptions) (grpc_health_v1.HealthClient, func()) {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(options)),
+		grpc.StreamInterceptor(StreamServerInterceptor(options)),
+	)
+	grpc_health_v1.RegisterHealthServer(server, healthServer{health.NewServer()})
+	go server.Serve(listener)
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, address string) (net.Conn, error) {
+			return listener.Dial()
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return grpc_health_v1.NewHealthClient(conn), func() {
+		conn.Close()
+		server.Stop()
+	}
+}
+
+func withMetadata(keyValues ...string) context.Context {
+	return metadata.NewOutgoingContext(context.Background(), metadata.Pairs(keyValues...))
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	defer startFakeCore().Close()
+	client, stop := startServer(t, InterceptorOptions{})
+	defer stop()
+
+	response, err := client.Check(withMetadata("authorization", "Bearer valid"), &grpc_health_v1.HealthCheckRequest{})
+	if err != nil || response.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Error("session was not verified", err)
+	}
+
+	_, err = client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	if status.Code(err) != codes.Unauthenticated || GetErrorReason(err) != UnauthorisedReason {
+		t.Error("missing access token was not rejected", err)
+	}
+
+	_, err = client.Check(withMetadata("authorization", "unknown"), &grpc_health_v1.HealthCheckRequest{})
+	if status.Code(err) != codes.Unauthenticated || GetErrorReason(err) != UnauthorisedReason {
+		t.Error("unknown access token was not rejected", err)
+	}
+
+	_, err = client.Check(withMetadata("authorization", "expired"), &grpc_health_v1.HealthCheckRequest{})
+	if status.Code(err) != codes.Unauthenticated || GetErrorReason(err) != TryRefreshTokenReason {
+		t.Error("expired access token did not ask to refresh", err)
+	}
+
+	var header metadata.MD
+	_, err = client.Check(withMetadata("authorization", "rotate"), &grpc_health_v1.HealthCheckRequest{},
+		grpc.Header(&header))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values := header.Get(NewAccessTokenMetadataKey); len(values) != 1 || values[0] != "rotated" {
+		t.Error("new access token was not sent", header)
+	}
+}
+
+func TestUnaryInterceptorOptions(t *testing.T) {
+	defer startFakeCore().Close()
+	client, stop := startServer(t, InterceptorOptions{
+		AntiCsrfMetadataKey: "anti-csrf",
+		SessionOptional:     true,
+	})
+	defer stop()
+
+	response, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	if err != nil || response.Status != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Error("call without session was not let through", err)
+	}
+
+	_, err = client.Check(withMetadata("authorization", "valid"), &grpc_health_v1.HealthCheckRequest{})
+	if GetErrorReason(err) != TryRefreshTokenReason {
+		t.Error("anti-csrf check was not done", err)
+	}
+
+	response, err = client.Check(withMetadata("authorization", "valid", "anti-csrf", "csrf"),
+		&grpc_health_v1.HealthCheckRequest{})
+	if err != nil || response.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Error("anti-csrf token was not sent to the core", err)
+	}
+}
+
+func TestStreamInterceptor(t *testing.T) {
+	defer startFakeCore().Close()
+	client, stop := startServer(t, InterceptorOptions{
+		Skip: func(fullMethod string) bool {
+			return fullMethod == "/grpc.health.v1.Health/Check"
+		},
+	})
+	defer stop()
+
+	stream, err := client.Watch(withMetadata("authorization", "valid"), &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Error("session was not verified", err)
+	}
+
+	stream, err = client.Watch(withMetadata("authorization", "expired"), &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); GetErrorReason(err) != TryRefreshTokenReason {
+		t.Error("expired access token did not ask to refresh", err)
+	}
+
+	response, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	if err != nil || response.Status != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Error("skipped method was verified", err)
+	}
+}
@@ -0,0 +1,183 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+// Package fakecore implements the core APIs used by the tests of the framework packages. The signing key it
+// hands out is always expired so that every session is verified by calling it.
+package fakecore
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/supertokens/supertokens-go/supertokens/core"
+)
+
+// Core keeps the sessions created by a test. Session handles are "handle-" followed by the user ID, so each user
+// has one JWT payload
+type Core struct {
+	lock          sync.Mutex
+	nextID        int
+	accessTokens  map[string]string
+	refreshTokens map[string]string
+	// usedRefreshTokens are reported as token theft when they are used again
+	usedRefreshTokens map[string]string
+	jwtPayloads       map[string]interface{}
+}
+
+// Start starts a fake core and resets the state of the core package. The caller has to configure supertokens
+// with the URL of the returned server and close it after the test
+func Start() (*httptest.Server, *Core) {
+	fake := &Core{
+		accessTokens:      map[string]string{},
+		refreshTokens:     map[string]string{},
+		usedRefreshTokens: map[string]string{},
+		jwtPayloads:       map[string]interface{}{},
+	}
+	server := httptest.NewServer(fake)
+	core.ResetQuerier()
+	core.ResetHandshakeInfo()
+	core.ResetError()
+	core.ResetVerifyCache()
+	return server, fake
+}
+
+// ModifyJWTPayload changes the JWT payload that the core has stored for userID
+func (fake *Core) ModifyJWTPayload(userID string, modify func(payload map[string]interface{})) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+	payload, _ := fake.jwtPayloads[userID].(map[string]interface{})
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+	modify(payload)
+	fake.jwtPayloads[userID] = payload
+}
+
+func (fake *Core) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+	var body map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&body)
+	var response map[string]interface{}
+	switch r.URL.Path {
+	case "/apiversion":
+		response = map[string]interface{}{"versions": []string{"2.3"}}
+	case "/handshake":
+		response = map[string]interface{}{
+			"status":                         "OK",
+			"jwtSigningPublicKey":            "key",
+			"jwtSigningPublicKeyExpiryTime":  0,
+			"cookieSecure":                   false,
+			"accessTokenPath":                "/",
+			"refreshTokenPath":               "/refresh",
+			"idRefreshTokenPath":             "/",
+			"enableAntiCsrf":                 false,
+			"accessTokenBlacklistingEnabled": false,
+			"cookieSameSite":                 "lax",
+			"sessionExpiredStatusCode":       401,
+		}
+	case "/session":
+		fake.jwtPayloads[body["userId"].(string)] = body["userDataInJWT"]
+		response = fake.newTokens(body["userId"].(string))
+	case "/session/verify":
+		userID, ok := fake.accessTokens[body["accessToken"].(string)]
+		if !ok {
+			response = map[string]interface{}{"status": "TRY_REFRESH_TOKEN", "message": "unknown access token"}
+			break
+		}
+		response = map[string]interface{}{
+			"status":                        "OK",
+			"session":                       fake.session(userID),
+			"jwtSigningPublicKey":           "key",
+			"jwtSigningPublicKeyExpiryTime": 0,
+		}
+	case "/session/refresh":
+		if userID, used := fake.usedRefreshTokens[body["refreshToken"].(string)]; used {
+			response = map[string]interface{}{"status": "TOKEN_THEFT_DETECTED", "session": fake.session(userID)}
+			break
+		}
+		userID, ok := fake.refreshTokens[body["refreshToken"].(string)]
+		if !ok {
+			response = map[string]interface{}{"status": "UNAUTHORISED", "message": "unknown refresh token"}
+			break
+		}
+		fake.usedRefreshTokens[body["refreshToken"].(string)] = userID
+		delete(fake.refreshTokens, body["refreshToken"].(string))
+		response = fake.newTokens(userID)
+	case "/jwt/data":
+		if r.Method == http.MethodPut {
+			userID := strings.TrimPrefix(body["sessionHandle"].(string), "handle-")
+			fake.jwtPayloads[userID] = body["userDataInJWT"]
+			response = map[string]interface{}{"status": "OK"}
+			break
+		}
+		userID := strings.TrimPrefix(r.URL.Query().Get("sessionHandle"), "handle-")
+		response = map[string]interface{}{"status": "OK", "userDataInJWT": fake.payload(userID)}
+	case "/session/regenerate":
+		userID := fake.accessTokens[body["accessToken"].(string)]
+		fake.jwtPayloads[userID] = body["userDataInJWT"]
+		response = map[string]interface{}{"status": "OK", "session": fake.session(userID)}
+	case "/session/remove":
+		response = map[string]interface{}{"status": "OK", "sessionHandlesRevoked": body["sessionHandles"]}
+	default:
+		w.WriteHeader(404)
+		return
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (fake *Core) newTokens(userID string) map[string]interface{} {
+	fake.nextID++
+	id := strconv.Itoa(fake.nextID)
+	fake.accessTokens["access"+id] = userID
+	fake.refreshTokens["refresh"+id] = userID
+	token := func(value string, path string) map[string]interface{} {
+		return map[string]interface{}{
+			"token":        value,
+			"expiry":       4102444800000,
+			"createdTime":  0,
+			"cookiePath":   path,
+			"cookieSecure": false,
+			"sameSite":     "lax",
+		}
+	}
+	return map[string]interface{}{
+		"status":         "OK",
+		"session":        fake.session(userID),
+		"accessToken":    token("access"+id, "/"),
+		"refreshToken":   token("refresh"+id, "/refresh"),
+		"idRefreshToken": token("idRefresh"+id, "/"),
+	}
+}
+
+func (fake *Core) session(userID string) map[string]interface{} {
+	return map[string]interface{}{
+		"handle":        "handle-" + userID,
+		"userId":        userID,
+		"userDataInJWT": fake.payload(userID),
+	}
+}
+
+func (fake *Core) payload(userID string) interface{} {
+	if payload, ok := fake.jwtPayloads[userID]; ok && payload != nil {
+		return payload
+	}
+	return map[string]interface{}{}
+}
@@ -0,0 +1,220 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+// DefaultRolesClaimPath is the path of the roles in the JWT payload if RolesClaimPath is not set
+const DefaultRolesClaimPath = "roles"
+
+// ClaimRequirement a check of one claim in the JWT payload of a session
+type ClaimRequirement struct {
+	// Path of the claim in the JWT payload, with nested keys separated by dots
+	Path string
+	// Check returns true if the value of the claim allows the request. The value is nil if the claim is missing
+	Check func(value interface{}) bool
+}
+
+// ClaimEquals function used to require the claim at path to be equal to expected
+func ClaimEquals(path string, expected interface{}) ClaimRequirement {
+	return ClaimRequirement{
+		Path: path,
+		Check: func(value interface{}) bool {
+			// numbers in the JWT payload are decoded as float64
+			if number, ok := toFloat64(expected); ok {
+				actual, ok := toFloat64(value)
+				return ok && actual == number
+			}
+			return reflect.DeepEqual(value, expected)
+		},
+	}
+}
+
+// ClaimIncludesAll function used to require the claim at path to be a list that has all of values
+func ClaimIncludesAll(path string, values ...string) ClaimRequirement {
+	return ClaimRequirement{
+		Path: path,
+		Check: func(value interface{}) bool {
+			list := getStringList(value)
+			for _, expected := range values {
+				if !containsString(list, expected) {
+					return false
+				}
+			}
+			return true
+		},
+	}
+}
+
+// ClaimIncludesAny function used to require the claim at path to be a list that has at least one of values
+func ClaimIncludesAny(path string, values ...string) ClaimRequirement {
+	return ClaimRequirement{
+		Path: path,
+		Check: func(value interface{}) bool {
+			list := getStringList(value)
+			for _, expected := range values {
+				if containsString(list, expected) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// GetClaim function used to get the value at path, with nested keys separated by dots, of a JWT payload
+func GetClaim(payload map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = payload
+	for _, key := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = object[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// CheckClaims function used to get a ForbiddenError if the JWT payload of session does not meet all requirements
+func CheckClaims(session Session, requirements ...ClaimRequirement) error {
+	payload := session.GetJWTPayload()
+	for _, requirement := range requirements {
+		value, _ := GetClaim(payload, requirement.Path)
+		if !requirement.Check(value) {
+			return errors.ForbiddenError{
+				Msg: "claim " + requirement.Path + " does not allow this request",
+			}
+		}
+	}
+	return nil
+}
+
+// RequireClaims returns a middleware that responds with a ForbiddenError unless the JWT payload meets all
+// requirements. It has to run after the session middleware
+func RequireClaims(requirements ...ClaimRequirement) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			session := GetSessionFromRequest(r)
+			if session == nil {
+				HandleErrorAndRespond(errors.UnauthorizedError{
+					Msg: "no session found for this request",
+				}, w)
+				return
+			}
+			if err := CheckClaims(*session, requirements...); err != nil {
+				HandleErrorAndRespond(err, w)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRole returns a middleware that responds with a ForbiddenError unless the session has at least one of
+// roles. It has to run after the session middleware
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return RequireClaims(ClaimIncludesAny(GetRolesClaimPath(), roles...))
+}
+
+// GetRolesClaimPath function used to get the path of the roles in the JWT payload
+func GetRolesClaimPath() string {
+	if configMap == nil || configMap.RolesClaimPath == "" {
+		return DefaultRolesClaimPath
+	}
+	return configMap.RolesClaimPath
+}
+
+// GetRoles function gets the roles in the JWT payload of this session
+func (session *Session) GetRoles() []string {
+	value, _ := GetClaim(session.GetJWTPayload(), GetRolesClaimPath())
+	return getStringList(value)
+}
+
+// HasAnyRole function returns true if this session has at least one of roles
+func (session *Session) HasAnyRole(roles ...string) bool {
+	sessionRoles := session.GetRoles()
+	for _, role := range roles {
+		if containsString(sessionRoles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAllRoles function returns true if this session has all of roles
+func (session *Session) HasAllRoles(roles ...string) bool {
+	sessionRoles := session.GetRoles()
+	for _, role := range roles {
+		if !containsString(sessionRoles, role) {
+			return false
+		}
+	}
+	return true
+}
+
+// getStringList accepts a single string as a list of one, since payloads are often written by hand
+func getStringList(value interface{}) []string {
+	switch actual := value.(type) {
+	case string:
+		return []string{actual}
+	case []string:
+		return actual
+	case []interface{}:
+		result := make([]string, 0, len(actual))
+		for _, item := range actual {
+			if str, ok := item.(string); ok {
+				result = append(result, str)
+			}
+		}
+		return result
+	}
+	return []string{}
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func toFloat64(value interface{}) (float64, bool) {
+	switch actual := value.(type) {
+	case float64:
+		return actual, true
+	case float32:
+		return float64(actual), true
+	case int:
+		return float64(actual), true
+	case int64:
+		return float64(actual), true
+	case uint64:
+		return float64(actual), true
+	}
+	return 0, false
+}
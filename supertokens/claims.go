@@ -0,0 +1,133 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/supertokens/supertokens-go/supertokens/core"
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+// ClaimFetchTimesKey is the key in the JWT payload under which the time in milliseconds at which each
+// SessionClaim was fetched is stored
+const ClaimFetchTimesKey = "st-claims-fetched"
+
+// SessionClaim a value in the JWT payload that is fetched by the app, refetched once it is stale and
+// validated whenever a session is verified
+type SessionClaim struct {
+	// Key of the claim in the JWT payload. Keys of nested objects are separated by dots, as in GetClaim
+	Key string
+	// Fetch gets the current value of the claim for userID. nil means the value is only set by the app
+	Fetch func(ctx context.Context, userID string) (interface{}, error)
+	// MaxAge is how long a fetched value is used before it is fetched again. 0 fetches it only if it is missing
+	MaxAge time.Duration
+	// Validate returns an error if the value does not allow the request. The value is nil if the claim is
+	// missing. nil accepts every value
+	Validate func(value interface{}) error
+}
+
+func getSessionClaims() []SessionClaim {
+	if configMap == nil {
+		return nil
+	}
+	return configMap.SessionClaims
+}
+
+// ValidateClaims function used to refetch the stale claims of this session and validate all claims. Refetched
+// values are saved in the JWT payload with a new access token. The first failed validation is returned as an
+// InvalidClaimError
+func (session *Session) ValidateClaims(ctx context.Context, claims ...SessionClaim) error {
+	if len(claims) == 0 {
+		return nil
+	}
+	if err := session.refetchStaleClaims(ctx, claims); err != nil {
+		return err
+	}
+	payload := session.GetJWTPayload()
+	for _, claim := range claims {
+		if claim.Validate == nil {
+			continue
+		}
+		value, _ := GetClaim(payload, claim.Key)
+		if err := claim.Validate(value); err != nil {
+			core.LogDebug("session claim is invalid", "claim", claim.Key, "userID", session.userID)
+			return errors.InvalidClaimError{
+				Msg:      "claim " + claim.Key + " is invalid: " + err.Error(),
+				ClaimKey: claim.Key,
+				Reason:   err.Error(),
+			}
+		}
+	}
+	return nil
+}
+
+func (session *Session) refetchStaleClaims(ctx context.Context, claims []SessionClaim) error {
+	payload := session.GetJWTPayload()
+	fetchTimes, _ := payload[ClaimFetchTimesKey].(map[string]interface{})
+	now := getCurrentTimeInMS()
+	patch := map[string]interface{}{}
+	newFetchTimes := map[string]interface{}{}
+	for _, claim := range claims {
+		if claim.Fetch == nil || !isClaimStale(claim, fetchTimes, now) {
+			continue
+		}
+		value, err := claim.Fetch(ctx, session.userID)
+		if err != nil {
+			return errors.GeneralError{
+				Msg:         "fetching claim " + claim.Key + " failed: " + err.Error(),
+				ActualError: err,
+			}
+		}
+		// a nil value removes the claim, and the fetch time keeps it from being fetched again right away
+		setClaimInPatch(patch, claim.Key, value)
+		newFetchTimes[claim.Key] = now
+	}
+	if len(newFetchTimes) == 0 {
+		return nil
+	}
+	patch[ClaimFetchTimesKey] = newFetchTimes
+	core.LogDebug("refetched stale session claims", "count", len(newFetchTimes), "userID", session.userID)
+	return session.MergeJWTPayload(patch)
+}
+
+// setClaimInPatch nests the value under each dot separated part of key, so merging the patch only
+// changes that claim and keeps the other keys of the objects it is in
+func setClaimInPatch(patch map[string]interface{}, key string, value interface{}) {
+	parts := strings.Split(key, ".")
+	for _, part := range parts[:len(parts)-1] {
+		nested, ok := patch[part].(map[string]interface{})
+		if !ok {
+			nested = map[string]interface{}{}
+			patch[part] = nested
+		}
+		patch = nested
+	}
+	patch[parts[len(parts)-1]] = value
+}
+
+func isClaimStale(claim SessionClaim, fetchTimes map[string]interface{}, now uint64) bool {
+	fetchedAt, ok := fetchTimes[claim.Key].(float64)
+	if !ok {
+		return true
+	}
+	// a fetch time in the future comes from a server whose clock is ahead
+	return claim.MaxAge > 0 && uint64(fetchedAt) < now &&
+		now-uint64(fetchedAt) > uint64(claim.MaxAge/time.Millisecond)
+}
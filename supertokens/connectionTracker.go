@@ -0,0 +1,240 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/supertokens/supertokens-go/supertokens/core"
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+// connectionTracker keeps the tracked connections of every session handle
+type connectionTracker struct {
+	lock        sync.Mutex
+	connections map[string]map[*trackedConnection]bool
+}
+
+type trackedConnection struct {
+	connection io.Closer
+	done       chan struct{}
+	finishOnce sync.Once
+}
+
+// finish stops watching the connection, and closes it if closeConnection is true
+func (tracked *trackedConnection) finish(closeConnection bool, reason string) {
+	tracked.finishOnce.Do(func() {
+		close(tracked.done)
+		if !closeConnection {
+			return
+		}
+		core.LogDebug("closing tracked connection", "reason", reason)
+		if err := tracked.connection.Close(); err != nil {
+			core.LogDebug("closing tracked connection failed", "error", err.Error())
+		}
+	})
+}
+
+var connectionTrackerInstantiated *connectionTracker
+var connectionTrackerOnce *sync.Once = new(sync.Once)
+
+func getConnectionTrackerInstance() *connectionTracker {
+	connectionTrackerOnce.Do(func() {
+		connectionTrackerInstantiated = &connectionTracker{
+			connections: map[string]map[*trackedConnection]bool{},
+		}
+	})
+	return connectionTrackerInstantiated
+}
+
+// ResetConnectionTracker to be used for testing only. Tracked connections are not closed
+func ResetConnectionTracker() {
+	tracker := getConnectionTrackerInstance()
+	tracker.lock.Lock()
+	for _, connections := range tracker.connections {
+		for tracked := range connections {
+			tracked.finish(false, "")
+		}
+	}
+	tracker.lock.Unlock()
+	connectionTrackerInstantiated = nil
+	connectionTrackerOnce = new(sync.Once)
+}
+
+func (tracker *connectionTracker) add(sessionHandle string, tracked *trackedConnection) {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+	if tracker.connections[sessionHandle] == nil {
+		tracker.connections[sessionHandle] = map[*trackedConnection]bool{}
+	}
+	tracker.connections[sessionHandle][tracked] = true
+}
+
+func (tracker *connectionTracker) remove(sessionHandle string, tracked *trackedConnection) {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+	delete(tracker.connections[sessionHandle], tracked)
+	if len(tracker.connections[sessionHandle]) == 0 {
+		delete(tracker.connections, sessionHandle)
+	}
+}
+
+func (tracker *connectionTracker) closeConnections(sessionHandle string) {
+	tracker.lock.Lock()
+	connections := tracker.connections[sessionHandle]
+	delete(tracker.connections, sessionHandle)
+	tracker.lock.Unlock()
+	for tracked := range connections {
+		tracked.finish(true, "session revoked")
+	}
+}
+
+// watch closes the connection when the access token expires or, if reverifyInterval is not 0, when the
+// access token is no longer accepted. Errors reaching the core do not close the connection
+func (tracker *connectionTracker) watch(sessionHandle string, tracked *trackedConnection,
+	accessToken string, expiry uint64, reverifyInterval time.Duration) {
+	var expiryTimer *time.Timer
+	var expired <-chan time.Time
+	if expiry != 0 {
+		expiryTimer = time.NewTimer(time.Until(time.Unix(0, int64(expiry)*int64(time.Millisecond))))
+		defer expiryTimer.Stop()
+		expired = expiryTimer.C
+	}
+	var reverify <-chan time.Time
+	if reverifyInterval > 0 {
+		ticker := time.NewTicker(reverifyInterval)
+		defer ticker.Stop()
+		reverify = ticker.C
+	}
+	for {
+		select {
+		case <-tracked.done:
+			return
+		case <-expired:
+			tracker.remove(sessionHandle, tracked)
+			tracked.finish(true, "access token expired")
+			return
+		case <-reverify:
+			session, err := core.GetSessionWithContext(context.Background(), accessToken, nil, false)
+			if err != nil {
+				if errors.IsUnauthorizedError(err) || errors.IsTryRefreshTokenError(err) {
+					tracker.remove(sessionHandle, tracked)
+					tracked.finish(true, err.Error())
+					return
+				}
+				core.LogDebug("verifying tracked connection failed", "sessionHandle", sessionHandle,
+					"error", err.Error())
+				continue
+			}
+			if session.AccessToken != nil {
+				accessToken = session.AccessToken.Token
+				if expiryTimer != nil && session.AccessToken.Expiry != 0 {
+					if !expiryTimer.Stop() {
+						// the timer fired after this case was selected, so its value has to be drained
+						select {
+						case <-expiryTimer.C:
+						default:
+						}
+					}
+					expiryTimer.Reset(time.Until(time.Unix(0, int64(session.AccessToken.Expiry)*int64(time.Millisecond))))
+				}
+			}
+		}
+	}
+}
+
+// GetSessionForWebSocket function used to verify the session of a WebSocket upgrade request. Browsers cannot send the
+// anti-csrf header with it, so the Origin header is checked against WebSocketAllowedOrigins instead
+func GetSessionForWebSocket(response http.ResponseWriter, request *http.Request) (Session, error) {
+	if err := checkWebSocketOrigin(request.Header.Get("Origin"), request.Host); err != nil {
+		return Session{}, err
+	}
+	return GetSession(response, request, false)
+}
+
+// GetSessionForWebSocketWithWrapper function used to verify the session of a WebSocket upgrade request for
+// frameworks that are not built on net/http
+func GetSessionForWebSocketWithWrapper(response ResponseWrapper, request RequestWrapper) (Session, error) {
+	if err := checkWebSocketOrigin(request.GetHeader("Origin"), request.GetHeader("Host")); err != nil {
+		return Session{}, err
+	}
+	return GetSessionWithWrapper(response, request, false)
+}
+
+// checkWebSocketOrigin returns a ForbiddenError if origin is neither allowed by WebSocketAllowedOrigins nor, if
+// that is empty, the origin of host. Requests without an Origin header do not come from browsers and are accepted
+func checkWebSocketOrigin(origin string, host string) error {
+	if origin == "" {
+		return nil
+	}
+	var allowedOrigins []string
+	if configMap != nil {
+		allowedOrigins = configMap.WebSocketAllowedOrigins
+	}
+	if len(allowedOrigins) == 0 {
+		parsedOrigin, err := url.Parse(origin)
+		if err == nil && host != "" && strings.EqualFold(parsedOrigin.Host, host) {
+			return nil
+		}
+	}
+	for _, allowedOrigin := range allowedOrigins {
+		if strings.EqualFold(strings.TrimSuffix(allowedOrigin, "/"), origin) {
+			return nil
+		}
+	}
+	core.LogDebug("websocket origin rejected", "origin", origin)
+	return errors.ForbiddenError{
+		Msg: "origin " + origin + " is not allowed to open a websocket",
+	}
+}
+
+// TrackConnection function used to close connection, for example a WebSocket connection, when its session is revoked
+// in this process or its access token expires. With ConnectionReverifyInterval set, it is also closed when the access
+// token fails to verify, which notices revocations in other processes if access token blacklisting is enabled.
+// The returned function stops tracking and has to be called once the connection is closed
+func TrackConnection(session Session, connection io.Closer) func() {
+	var reverifyInterval time.Duration
+	if configMap != nil {
+		reverifyInterval = configMap.ConnectionReverifyInterval
+	}
+	tracker := getConnectionTrackerInstance()
+	tracked := &trackedConnection{
+		connection: connection,
+		done:       make(chan struct{}),
+	}
+	tracker.add(session.sessionHandle, tracked)
+	go tracker.watch(session.sessionHandle, tracked, session.accessToken, session.GetAccessTokenExpiry(),
+		reverifyInterval)
+	return func() {
+		tracker.remove(session.sessionHandle, tracked)
+		tracked.finish(false, "")
+	}
+}
+
+// GetTrackedConnectionCount function used to get the number of open connections tracked for a session
+func GetTrackedConnectionCount(sessionHandle string) int {
+	tracker := getConnectionTrackerInstance()
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+	return len(tracker.connections[sessionHandle])
+}
@@ -17,6 +17,7 @@
 package supertokens
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -36,23 +37,120 @@ const antiCsrfHeaderKey = "anti-csrf"
 const frontendSDKNameHeaderKey = "supertokens-sdk-name"
 const frontendSDKVersionHeaderKey = "supertokens-sdk-version"
 
+const accessTokenExpiryHeaderKey = "st-access-token-expiry"
+const refreshRecommendedHeaderKey = "st-refresh-recommended"
+
 var configMap *ConfigMap = nil
 
+// RequestWrapper gives access to a request, so that frameworks that are not built on net/http can be supported
+type RequestWrapper = core.RequestWrapper
+
+// ResponseWrapper gives access to the headers and cookies of a response
+type ResponseWrapper interface {
+	GetHeader(key string) string
+	SetHeader(key string, value string)
+	SetCookie(cookie *http.Cookie)
+}
+
+type httpRequestWrapper struct {
+	request *http.Request
+}
+
+func wrapRequest(request *http.Request) RequestWrapper {
+	return httpRequestWrapper{request: request}
+}
+
+// wrapRequestIfPresent returns nil instead of a wrapper of a nil request
+func wrapRequestIfPresent(request *http.Request) RequestWrapper {
+	if request == nil {
+		return nil
+	}
+	return wrapRequest(request)
+}
+
+// getHTTPRequest returns the request that request wraps, or nil if it does not wrap a *http.Request
+func getHTTPRequest(request RequestWrapper) *http.Request {
+	wrapper, ok := request.(httpRequestWrapper)
+	if !ok {
+		return nil
+	}
+	return wrapper.request
+}
+
+func (wrapper httpRequestWrapper) Context() context.Context {
+	return wrapper.request.Context()
+}
+
+func (wrapper httpRequestWrapper) GetHeader(key string) string {
+	return wrapper.request.Header.Get(key)
+}
+
+func (wrapper httpRequestWrapper) GetCookie(key string) (string, bool) {
+	cookie, err := wrapper.request.Cookie(key)
+	if err != nil {
+		return "", false
+	}
+	return cookie.Value, true
+}
+
+func (wrapper httpRequestWrapper) GetMethod() string {
+	return wrapper.request.Method
+}
+
+func (wrapper httpRequestWrapper) GetPath() string {
+	return wrapper.request.URL.Path
+}
+
+func (wrapper httpRequestWrapper) GetRemoteAddr() string {
+	return wrapper.request.RemoteAddr
+}
+
+type httpResponseWrapper struct {
+	response http.ResponseWriter
+}
+
+func wrapResponse(response http.ResponseWriter) ResponseWrapper {
+	return httpResponseWrapper{response: response}
+}
+
+func (wrapper httpResponseWrapper) GetHeader(key string) string {
+	return wrapper.response.Header().Get(key)
+}
+
+func (wrapper httpResponseWrapper) SetHeader(key string, value string) {
+	wrapper.response.Header().Set(key, value)
+}
+
+func (wrapper httpResponseWrapper) SetCookie(cookie *http.Cookie) {
+	http.SetCookie(wrapper.response, cookie)
+}
+
+// noopResponseWrapper is used by sessions that were not read from a request with cookies
+type noopResponseWrapper struct{}
+
+func (wrapper noopResponseWrapper) GetHeader(key string) string {
+	return ""
+}
+
+func (wrapper noopResponseWrapper) SetHeader(key string, value string) {}
+
+func (wrapper noopResponseWrapper) SetCookie(cookie *http.Cookie) {}
+
 func configCookieAndHeaders(config ConfigMap) {
 	configMap = &config
 }
 
-func attachAccessTokenToCookie(response http.ResponseWriter, token string,
+func attachAccessTokenToCookie(response ResponseWrapper, token string,
 	expiry uint64, domain *string, secure bool, path string, sameSite string) {
 	setCookie(response, accessTokenCookieKey, token, domain, secure, true, expiry, path, sameSite)
 }
 
-func attachRefreshTokenToCookie(response http.ResponseWriter, token string,
+func attachRefreshTokenToCookie(response ResponseWrapper, token string,
 	expiry uint64, domain *string, secure bool, path string, sameSite string) {
 	setCookie(response, refreshTokenCookieKey, token, domain, secure, true, expiry, path, sameSite)
 }
 
-func setIDRefreshTokenInHeaderAndCookie(response http.ResponseWriter, token string,
+func setIDRefreshTokenInHeaderAndCookie(response ResponseWrapper, token string,
 	expiry uint64, domain *string, secure bool, path string, sameSite string) {
 	setHeader(response, idRefreshTokenHeaderKey, token+";"+fmt.Sprint(expiry))
 	setHeader(response, "Access-Control-Expose-Headers", idRefreshTokenHeaderKey)
@@ -60,12 +158,26 @@ func setIDRefreshTokenInHeaderAndCookie(response http.ResponseWriter, token stri
 	setCookie(response, idRefreshTokenCookieKey, token, domain, secure, true, expiry, path, sameSite)
 }
 
-func setAntiCsrfTokenInHeaders(response http.ResponseWriter, antiCsrfToken string) {
+func setAntiCsrfTokenInHeaders(response ResponseWrapper, antiCsrfToken string) {
 	setHeader(response, antiCsrfHeaderKey, antiCsrfToken)
 	setHeader(response, "Access-Control-Expose-Headers", antiCsrfHeaderKey)
 }
 
-func saveFrontendInfoFromRequest(request *http.Request) {
+func setRefreshHintInHeaders(response ResponseWrapper, accessTokenExpiry uint64) {
+	if configMap == nil || configMap.RefreshHintWindow <= 0 || accessTokenExpiry == 0 {
+		return
+	}
+	expiry := time.Unix(0, int64(accessTokenExpiry)*int64(time.Millisecond))
+	if time.Until(expiry) > configMap.RefreshHintWindow {
+		return
+	}
+	setHeader(response, accessTokenExpiryHeaderKey, fmt.Sprint(accessTokenExpiry))
+	setHeader(response, refreshRecommendedHeaderKey, "true")
+	setHeader(response, "Access-Control-Expose-Headers", accessTokenExpiryHeaderKey)
+	setHeader(response, "Access-Control-Expose-Headers", refreshRecommendedHeaderKey)
+}
+
+func saveFrontendInfoFromRequest(request RequestWrapper) {
 	name := getHeader(request, frontendSDKNameHeaderKey)
 	version := getHeader(request, frontendSDKVersionHeaderKey)
 	if name != nil && version != nil {
@@ -73,20 +185,21 @@ func saveFrontendInfoFromRequest(request *http.Request) {
 	}
 }
 
-func getAccessTokenFromCookie(request *http.Request) *string {
+func getAccessTokenFromCookie(request RequestWrapper) *string {
 	return getCookieValue(request, accessTokenCookieKey)
 }
 
-func getAntiCsrfTokenFromHeaders(request *http.Request) *string {
+func getAntiCsrfTokenFromHeaders(request RequestWrapper) *string {
 	return getHeader(request, antiCsrfHeaderKey)
 }
 
-func getIDRefreshTokenFromCookie(request *http.Request) *string {
+func getIDRefreshTokenFromCookie(request RequestWrapper) *string {
 	return getCookieValue(request, idRefreshTokenCookieKey)
 }
 
-func clearSessionFromCookie(response http.ResponseWriter, domain *string,
+func clearSessionFromCookie(response ResponseWrapper, domain *string,
 	secure bool, accessTokenPath string, refreshTokenPath string, idRefreshTokenPath string, sameSite string) {
+	core.LogDebug("clearing session cookies")
 	setCookie(response, accessTokenCookieKey, "", domain, secure, true, 0, accessTokenPath, sameSite)
 	setCookie(response, refreshTokenCookieKey, "", domain, secure, true, 0, refreshTokenPath, sameSite)
 	setCookie(response, idRefreshTokenCookieKey, "", domain, secure, true, 0, idRefreshTokenPath, sameSite)
@@ -94,11 +207,11 @@ func clearSessionFromCookie(response http.ResponseWriter, domain *string,
 	setHeader(response, "Access-Control-Expose-Headers", idRefreshTokenHeaderKey)
 }
 
-func getRefreshTokenFromCookie(request *http.Request) *string {
+func getRefreshTokenFromCookie(request RequestWrapper) *string {
 	return getCookieValue(request, refreshTokenCookieKey)
 }
 
-func setCookie(response http.ResponseWriter, name string, value string,
+func setCookie(response ResponseWrapper, name string, value string,
 	domain *string, secure bool, httpOnly bool, expires uint64, path string, sameSite string) {
 
 	if configMap != nil {
@@ -141,7 +254,7 @@ func setCookie(response http.ResponseWriter, name string, value string,
 			Path:     path,
 			SameSite: sameSiteField,
 		}
-		http.SetCookie(response, &cookie)
+		response.SetCookie(&cookie)
 	} else {
 		cookie := http.Cookie{
 			Name:     name,
@@ -152,42 +265,40 @@ func setCookie(response http.ResponseWriter, name string, value string,
 			Path:     path,
 			SameSite: sameSiteField,
 		}
-		http.SetCookie(response, &cookie)
+		response.SetCookie(&cookie)
 	}
 }
 
-func setHeader(response http.ResponseWriter, key string, value string) {
-	existingValue := response.Header().Get(strings.ToLower(key))
+func setHeader(response ResponseWrapper, key string, value string) {
+	existingValue := response.GetHeader(strings.ToLower(key))
 	if existingValue == "" {
-		response.Header().Set(key, value)
+		response.SetHeader(key, value)
 	} else {
-		response.Header().Set(key, existingValue+", "+value)
+		response.SetHeader(key, existingValue+", "+value)
 	}
 }
 
-func getHeader(request *http.Request, key string) *string {
-	value := request.Header.Get(key)
+func getHeader(request RequestWrapper, key string) *string {
+	value := request.GetHeader(key)
 	if value == "" {
 		return nil
 	}
 	return &value
 }
 
-func getCookieValue(request *http.Request, key string) *string {
-	cookies := request.Cookies()
-	for _, value := range cookies {
-		if value.Name == key {
-			val, err := url.QueryUnescape(value.Value)
-			if err != nil {
-				return nil
-			}
-			return &val
-		}
+func getCookieValue(request RequestWrapper, key string) *string {
+	value, ok := request.GetCookie(key)
+	if !ok {
+		return nil
+	}
+	val, err := url.QueryUnescape(value)
+	if err != nil {
+		return nil
 	}
-	return nil
+	return &val
 }
 
-func setRelevantHeadersForOptionsAPI(response http.ResponseWriter) {
+func setRelevantHeadersForOptionsAPI(response ResponseWrapper) {
 	setHeader(response, "Access-Control-Allow-Headers", antiCsrfHeaderKey)
 	setHeader(response, "Access-Control-Allow-Headers", frontendSDKNameHeaderKey)
 	setHeader(response, "Access-Control-Allow-Headers", frontendSDKVersionHeaderKey)
@@ -0,0 +1,160 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"sync"
+
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+// DefaultDegradedModeMaxStaleness is used if degraded mode is enabled without a max staleness (5 mins)
+const DefaultDegradedModeMaxStaleness uint64 = 300000
+
+// DegradedModeStats carrier of information about sessions verified while the core was unreachable
+type DegradedModeStats struct {
+	Active                  bool
+	LastCoreContactTime     uint64
+	TimesEntered            uint64
+	SessionsAccepted        uint64
+	SessionsRejectedAsStale uint64
+}
+
+type degradedMode struct {
+	enabled         bool
+	maxStaleness    uint64
+	stats           DegradedModeStats
+	onChangeHandler func(active bool, err error)
+}
+
+var degradedModeInstantiated *degradedMode
+var degradedModeLock sync.Mutex
+
+// GetDegradedModeInstance returns the state of degraded mode
+func GetDegradedModeInstance() *degradedMode {
+	degradedModeLock.Lock()
+	defer degradedModeLock.Unlock()
+	if degradedModeInstantiated == nil {
+		degradedModeInstantiated = &degradedMode{
+			enabled:      false,
+			maxStaleness: DefaultDegradedModeMaxStaleness,
+		}
+	}
+	return degradedModeInstantiated
+}
+
+// ConfigDegradedMode enables accepting locally verified access tokens while the core is unreachable
+func ConfigDegradedMode(enabled bool, maxStalenessInMS uint64) {
+	mode := GetDegradedModeInstance()
+	degradedModeLock.Lock()
+	defer degradedModeLock.Unlock()
+	mode.enabled = enabled
+	mode.maxStaleness = maxStalenessInMS
+	if maxStalenessInMS == 0 {
+		mode.maxStaleness = DefaultDegradedModeMaxStaleness
+	}
+}
+
+// ResetDegradedMode to be used for testing only
+func ResetDegradedMode() {
+	degradedModeLock.Lock()
+	defer degradedModeLock.Unlock()
+	degradedModeInstantiated = nil
+}
+
+// SetOnChangeHandler sets a function that is called when degraded mode is entered or left
+func (mode *degradedMode) SetOnChangeHandler(handler func(active bool, err error)) {
+	degradedModeLock.Lock()
+	defer degradedModeLock.Unlock()
+	mode.onChangeHandler = handler
+}
+
+// GetStats returns a snapshot of degraded mode counters
+func (mode *degradedMode) GetStats() DegradedModeStats {
+	degradedModeLock.Lock()
+	defer degradedModeLock.Unlock()
+	return mode.stats
+}
+
+func (mode *degradedMode) recordCoreContact() {
+	degradedModeLock.Lock()
+	mode.stats.LastCoreContactTime = getCurrTimeInMS()
+	wasActive := mode.stats.Active
+	mode.stats.Active = false
+	handler := mode.onChangeHandler
+	degradedModeLock.Unlock()
+	if wasActive && handler != nil {
+		handler(false, nil)
+	}
+}
+
+// restoreLastCoreContact is used when the handshake info was loaded from a HandshakeStore
+func (mode *degradedMode) restoreLastCoreContact(contactTime uint64) {
+	degradedModeLock.Lock()
+	defer degradedModeLock.Unlock()
+	if contactTime > mode.stats.LastCoreContactTime {
+		mode.stats.LastCoreContactTime = contactTime
+	}
+}
+
+// accept decides if a token that was verified locally can be used even though the core could not be reached
+func (mode *degradedMode) accept(coreError error) bool {
+	degradedModeLock.Lock()
+	if !mode.enabled || !isCoreUnreachableError(coreError) {
+		degradedModeLock.Unlock()
+		return false
+	}
+	if getCurrTimeInMS()-mode.stats.LastCoreContactTime > mode.maxStaleness {
+		mode.stats.SessionsRejectedAsStale++
+		degradedModeLock.Unlock()
+		return false
+	}
+	mode.stats.SessionsAccepted++
+	wasActive := mode.stats.Active
+	if !wasActive {
+		mode.stats.Active = true
+		mode.stats.TimesEntered++
+	}
+	handler := mode.onChangeHandler
+	degradedModeLock.Unlock()
+	if !wasActive && handler != nil {
+		handler(true, coreError)
+	}
+	return true
+}
+
+func isCoreUnreachableError(err error) bool {
+	generalError, ok := err.(errors.GeneralError)
+	if !ok {
+		return false
+	}
+	if generalError.ActualError == nil {
+		return generalError.Msg == noCoreAvailableMessage
+	}
+	if _, ok := generalError.ActualError.(errors.GeneralError); ok {
+		return isCoreUnreachableError(generalError.ActualError)
+	}
+	if urlError, ok := generalError.ActualError.(*url.Error); ok && urlError.Err == context.Canceled {
+		// the request was cancelled by the caller, not failed by the core
+		return false
+	}
+	_, isNetError := generalError.ActualError.(net.Error)
+	return isNetError
+}
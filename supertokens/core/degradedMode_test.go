@@ -0,0 +1,90 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"context"
+	goErrors "errors"
+	"net/url"
+	"testing"
+
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+func TestCoreUnreachableErrors(t *testing.T) {
+	if !isCoreUnreachableError(errors.GeneralError{Msg: noCoreAvailableMessage}) {
+		t.Error("no core available should be unreachable")
+	}
+	netError := &url.Error{Op: "Post", URL: "http://localhost:8080", Err: goErrors.New("timeout")}
+	if !isCoreUnreachableError(errors.GeneralError{Msg: netError.Error(), ActualError: netError}) {
+		t.Error("network error should be unreachable")
+	}
+	wrapped := errors.GeneralError{Msg: noCoreAvailableMessage}
+	if !isCoreUnreachableError(errors.GeneralError{Msg: wrapped.Msg, ActualError: wrapped}) {
+		t.Error("wrapped no core available should be unreachable")
+	}
+	cancelled := &url.Error{Op: "Post", URL: "http://localhost:8080", Err: context.Canceled}
+	if isCoreUnreachableError(errors.GeneralError{Msg: cancelled.Error(), ActualError: cancelled}) {
+		t.Error("cancelled request should not be unreachable")
+	}
+	if isCoreUnreachableError(errors.GeneralError{Msg: context.Canceled.Error(), ActualError: context.Canceled}) {
+		t.Error("cancelled wait should not be unreachable")
+	}
+	if isCoreUnreachableError(errors.GeneralError{Msg: "500"}) {
+		t.Error("error status from core should not be unreachable")
+	}
+	if isCoreUnreachableError(errors.UnauthorizedError{Msg: noCoreAvailableMessage}) {
+		t.Error("unauthorised should not be unreachable")
+	}
+}
+
+func TestDegradedModeAcceptance(t *testing.T) {
+	ResetDegradedMode()
+	defer ResetDegradedMode()
+	coreError := errors.GeneralError{Msg: noCoreAvailableMessage}
+	mode := GetDegradedModeInstance()
+
+	if mode.accept(coreError) {
+		t.Error("degraded mode should be disabled by default")
+	}
+
+	ConfigDegradedMode(true, 10000)
+	if mode.accept(coreError) {
+		t.Error("core was never contacted, so there is nothing to fall back on")
+	}
+
+	changes := []bool{}
+	mode.SetOnChangeHandler(func(active bool, err error) {
+		changes = append(changes, active)
+	})
+	mode.recordCoreContact()
+	if !mode.accept(coreError) || !mode.accept(coreError) {
+		t.Error("session should have been accepted")
+	}
+	if mode.accept(errors.GeneralError{Msg: "500"}) {
+		t.Error("core was reachable, so session should not have been accepted")
+	}
+	mode.recordCoreContact()
+
+	stats := mode.GetStats()
+	if stats.Active || stats.TimesEntered != 1 || stats.SessionsAccepted != 2 || stats.SessionsRejectedAsStale != 1 {
+		t.Error("incorrect stats", stats)
+	}
+	if len(changes) != 2 || !changes[0] || changes[1] {
+		t.Error("incorrect change notifications", changes)
+	}
+}
@@ -22,10 +22,13 @@ import (
 )
 
 type errorHandlers struct {
-	OnTokenTheftDetectedErrorHandler func(sessionHandle string, userID string, response http.ResponseWriter)
-	OnUnauthorizedErrorHandler       func(error, http.ResponseWriter)
-	OnTryRefreshTokenErrorHandler    func(error, http.ResponseWriter)
-	OnGeneralErrorHandler            func(error, http.ResponseWriter)
+	OnTokenTheftDetectedErrorHandler  func(sessionHandle string, userID string, response http.ResponseWriter)
+	OnUnauthorizedErrorHandler        func(error, http.ResponseWriter)
+	OnTryRefreshTokenErrorHandler     func(error, http.ResponseWriter)
+	OnGeneralErrorHandler             func(error, http.ResponseWriter)
+	OnForbiddenErrorHandler           func(error, http.ResponseWriter)
+	OnInvalidClaimErrorHandler        func(error, http.ResponseWriter)
+	OnReauthenticationRequiredHandler func(error, http.ResponseWriter)
 }
 
 func defaultTokenTheftDetectedErrorHandler(sessionHandle string, userID string, w http.ResponseWriter) {
@@ -36,7 +39,37 @@ func defaultTokenTheftDetectedErrorHandler(sessionHandle string, userID string,
 	}
 	w.WriteHeader(handshakeInfo.SessionExpiredStatusCode)
 	w.Write([]byte("token theft detected"))
-	_, _ = RevokeSession(sessionHandle)
+	RevokeSessionAfterTokenTheft(sessionHandle, userID)
+}
+
+// RevokeSessionAfterTokenTheft revokes the affected session, as the default token theft handlers do, unless a
+// TokenTheftPolicy is configured. The policy then revokes the sessions in the background
+func RevokeSessionAfterTokenTheft(sessionHandle string, userID string) {
+	if GetTokenTheftPolicyInstance().GetPolicy() == nil {
+		getTokenTheftRevoker()(sessionHandle, userID)
+	}
+}
+
+var tokenTheftRevoker func(sessionHandle string, userID string)
+var tokenTheftRevokerLock sync.Mutex
+
+// ConfigTokenTheftRevoker sets how the default token theft handler revokes the affected session, so that
+// the supertokens package can also close its connections and call its hooks. nil only revokes it in the core
+func ConfigTokenTheftRevoker(revoke func(sessionHandle string, userID string)) {
+	tokenTheftRevokerLock.Lock()
+	defer tokenTheftRevokerLock.Unlock()
+	tokenTheftRevoker = revoke
+}
+
+func getTokenTheftRevoker() func(sessionHandle string, userID string) {
+	tokenTheftRevokerLock.Lock()
+	defer tokenTheftRevokerLock.Unlock()
+	if tokenTheftRevoker == nil {
+		return func(sessionHandle string, userID string) {
+			_, _ = RevokeSession(sessionHandle)
+		}
+	}
+	return tokenTheftRevoker
 }
 
 func defaultUnauthorizedErrorHandler(err error, w http.ResponseWriter) {
@@ -59,6 +92,21 @@ func defaultTryRefreshTokenErrorHandler(err error, w http.ResponseWriter) {
 	w.Write([]byte("try refresh token: " + err.Error()))
 }
 
+func defaultForbiddenErrorHandler(err error, w http.ResponseWriter) {
+	w.WriteHeader(http.StatusForbidden)
+	w.Write([]byte("Forbidden: " + err.Error()))
+}
+
+func defaultInvalidClaimErrorHandler(err error, w http.ResponseWriter) {
+	w.WriteHeader(http.StatusForbidden)
+	w.Write([]byte("Invalid claim: " + err.Error()))
+}
+
+func defaultReauthenticationRequiredHandler(err error, w http.ResponseWriter) {
+	w.WriteHeader(http.StatusForbidden)
+	w.Write([]byte("Reauthentication required: " + err.Error()))
+}
+
 func defaultGeneralErrorHandler(err error, w http.ResponseWriter) {
 	w.WriteHeader(500)
 	w.Write([]byte("Internal error: " + err.Error()))
@@ -71,10 +119,13 @@ var errorHandlersOnce *sync.Once = new(sync.Once)
 func GetErrorHandlersInstance() *errorHandlers {
 	errorHandlersOnce.Do(func() {
 		errorHandlerInstantiated = &errorHandlers{
-			OnTokenTheftDetectedErrorHandler: defaultTokenTheftDetectedErrorHandler,
-			OnUnauthorizedErrorHandler:       defaultUnauthorizedErrorHandler,
-			OnTryRefreshTokenErrorHandler:    defaultTryRefreshTokenErrorHandler,
-			OnGeneralErrorHandler:            defaultGeneralErrorHandler,
+			OnTokenTheftDetectedErrorHandler:  defaultTokenTheftDetectedErrorHandler,
+			OnUnauthorizedErrorHandler:        defaultUnauthorizedErrorHandler,
+			OnTryRefreshTokenErrorHandler:     defaultTryRefreshTokenErrorHandler,
+			OnGeneralErrorHandler:             defaultGeneralErrorHandler,
+			OnForbiddenErrorHandler:           defaultForbiddenErrorHandler,
+			OnInvalidClaimErrorHandler:        defaultInvalidClaimErrorHandler,
+			OnReauthenticationRequiredHandler: defaultReauthenticationRequiredHandler,
 		}
 	})
 	return errorHandlerInstantiated
@@ -44,6 +44,7 @@ func GetHandshakeInfoInstance() (*handshakeInfo, error) {
 		if handshakeInfoInstantiated == nil {
 			response, err := GetQuerierInstance().SendPostRequest("handshake", "/handshake", map[string]interface{}{})
 			if err != nil {
+				LogDebug("handshake failed", "error", err.Error())
 				return nil, err
 			}
 			var domain *string = nil
@@ -64,6 +65,8 @@ func GetHandshakeInfoInstance() (*handshakeInfo, error) {
 				IDRefreshTokenPath:             response["idRefreshTokenPath"].(string),
 				SessionExpiredStatusCode:       int(response["sessionExpiredStatusCode"].(float64)),
 			}
+			LogDebug("handshake done", "jwtSigningPublicKeyExpiryTime", handshakeInfoInstantiated.JwtSigningPublicKeyExpiryTime)
+			saveHandshakeInfo(*handshakeInfoInstantiated)
 		}
 	}
 	return handshakeInfoInstantiated, nil
@@ -73,9 +76,17 @@ var handshakeInfoLock sync.Mutex
 
 func (info *handshakeInfo) UpdateJwtSigningPublicKeyInfo(newKey string, newExpiry uint64) {
 	handshakeInfoLock.Lock()
-	defer handshakeInfoLock.Unlock()
+	if info.JwtSigningPublicKey == newKey && info.JwtSigningPublicKeyExpiryTime == newExpiry {
+		// every verify with the core returns the key, so only changes are saved
+		handshakeInfoLock.Unlock()
+		return
+	}
+	LogDebug("jwt signing key updated", "jwtSigningPublicKeyExpiryTime", newExpiry)
 	info.JwtSigningPublicKey = newKey
 	info.JwtSigningPublicKeyExpiryTime = newExpiry
+	updatedInfo := *info
+	handshakeInfoLock.Unlock()
+	saveHandshakeInfo(updatedInfo)
 }
 
 // ResetHandshakeInfo to be used for testing only
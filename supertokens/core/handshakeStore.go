@@ -0,0 +1,182 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// HandshakeStore persists the handshake info and CDI version so that a new process can verify
+// sessions without querying the core first. Load must return nil data if nothing has been saved yet.
+type HandshakeStore interface {
+	Load() ([]byte, error)
+	Save(data []byte) error
+}
+
+type storedHandshake struct {
+	Hosts         string        `json:"hosts"`
+	APIVersion    string        `json:"apiVersion"`
+	SavedTime     uint64        `json:"savedTime"`
+	HandshakeInfo handshakeInfo `json:"handshakeInfo"`
+}
+
+var handshakeStoreInstantiated HandshakeStore
+var handshakeStoreLock sync.Mutex
+
+// ConfigHandshakeStore sets the store and restores handshake info from it if it is still valid
+func ConfigHandshakeStore(store HandshakeStore) {
+	handshakeStoreLock.Lock()
+	handshakeStoreInstantiated = store
+	handshakeStoreLock.Unlock()
+	if store == nil {
+		return
+	}
+	data, err := store.Load()
+	if err != nil || data == nil {
+		return
+	}
+	var stored storedHandshake
+	if json.Unmarshal(data, &stored) != nil {
+		return
+	}
+	querierInstance := GetQuerierInstance()
+	if stored.Hosts != strings.Join(querierInstance.hosts, ";") ||
+		stored.HandshakeInfo.JwtSigningPublicKeyExpiryTime <= getCurrTimeInMS() ||
+		getLargestVersionFromIntersection([]string{stored.APIVersion}, CdiVersion) == nil {
+		LogDebug("stored handshake info is expired or does not match the config")
+		return
+	}
+
+	apiVersionLock.Lock()
+	if querierInstance.apiVersion == nil {
+		apiVersion := stored.APIVersion
+		querierInstance.apiVersion = &apiVersion
+	}
+	apiVersionLock.Unlock()
+
+	handshakeInfoLock.Lock()
+	if handshakeInfoInstantiated == nil {
+		info := stored.HandshakeInfo
+		handshakeInfoInstantiated = &info
+	}
+	handshakeInfoLock.Unlock()
+
+	GetDegradedModeInstance().restoreLastCoreContact(stored.SavedTime)
+	LogDebug("handshake info restored from store", "savedTime", stored.SavedTime)
+}
+
+// ResetHandshakeStore to be used for testing only
+func ResetHandshakeStore() {
+	handshakeStoreLock.Lock()
+	defer handshakeStoreLock.Unlock()
+	handshakeStoreInstantiated = nil
+}
+
+func saveHandshakeInfo(info handshakeInfo) {
+	handshakeStoreLock.Lock()
+	store := handshakeStoreInstantiated
+	handshakeStoreLock.Unlock()
+	if store == nil {
+		return
+	}
+	querierInstance := GetQuerierInstance()
+	apiVersion := querierInstance.loadAPIVersion()
+	hosts := strings.Join(querierInstance.hosts, ";")
+	if apiVersion == nil {
+		return
+	}
+	data, err := json.Marshal(storedHandshake{
+		Hosts:         hosts,
+		APIVersion:    *apiVersion,
+		SavedTime:     getCurrTimeInMS(),
+		HandshakeInfo: info,
+	})
+	if err != nil {
+		return
+	}
+	_ = store.Save(data)
+}
+
+type fileHandshakeStore struct {
+	path string
+	lock sync.Mutex
+}
+
+// NewFileHandshakeStore returns a HandshakeStore that keeps the handshake info in a file at path
+func NewFileHandshakeStore(path string) HandshakeStore {
+	return &fileHandshakeStore{
+		path: path,
+	}
+}
+
+func (store *fileHandshakeStore) Load() ([]byte, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	data, err := ioutil.ReadFile(store.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (store *fileHandshakeStore) Save(data []byte) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	// write to a temp file first so that other processes never read a partially written file
+	tempFile, err := ioutil.TempFile(filepath.Dir(store.path), filepath.Base(store.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tempFile.Write(data)
+	closeErr := tempFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tempFile.Name())
+		return err
+	}
+	return os.Rename(tempFile.Name(), store.path)
+}
+
+type inMemoryHandshakeStore struct {
+	data []byte
+	lock sync.Mutex
+}
+
+// NewInMemoryHandshakeStore returns a HandshakeStore that keeps the handshake info for the lifetime of the process
+func NewInMemoryHandshakeStore() HandshakeStore {
+	return &inMemoryHandshakeStore{}
+}
+
+func (store *inMemoryHandshakeStore) Load() ([]byte, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	return store.data, nil
+}
+
+func (store *inMemoryHandshakeStore) Save(data []byte) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	store.data = data
+	return nil
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func resetForHandshakeStoreTest() {
+	ResetQuerier()
+	ResetHandshakeInfo()
+	ResetHandshakeStore()
+	ResetDegradedMode()
+}
+
+func TestHandshakeInfoIsRestoredFromStore(t *testing.T) {
+	resetForHandshakeStoreTest()
+	defer resetForHandshakeStoreTest()
+	InitQuerier("http://localhost:8080", "")
+	store := NewInMemoryHandshakeStore()
+	ConfigHandshakeStore(store)
+
+	apiVersion := "2.3"
+	GetQuerierInstance().apiVersion = &apiVersion
+	saveHandshakeInfo(handshakeInfo{
+		JwtSigningPublicKey:           "key",
+		JwtSigningPublicKeyExpiryTime: getCurrTimeInMS() + 10000,
+		SessionExpiredStatusCode:      440,
+	})
+
+	ResetQuerier()
+	ResetHandshakeInfo()
+	InitQuerier("http://localhost:8080", "")
+	ConfigHandshakeStore(store)
+
+	if handshakeInfoInstantiated == nil || handshakeInfoInstantiated.JwtSigningPublicKey != "key" ||
+		handshakeInfoInstantiated.SessionExpiredStatusCode != 440 {
+		t.Error("handshake info was not restored")
+	}
+	version, err := GetQuerierInstance().GetAPIVersion()
+	if err != nil || version != "2.3" {
+		t.Error("api version was not restored")
+	}
+	if GetDegradedModeInstance().GetStats().LastCoreContactTime == 0 {
+		t.Error("last core contact was not restored")
+	}
+}
+
+func TestExpiredOrMismatchedHandshakeInfoIsNotRestored(t *testing.T) {
+	resetForHandshakeStoreTest()
+	defer resetForHandshakeStoreTest()
+	InitQuerier("http://localhost:8080", "")
+	store := NewInMemoryHandshakeStore()
+	ConfigHandshakeStore(store)
+
+	apiVersion := "2.3"
+	GetQuerierInstance().apiVersion = &apiVersion
+	saveHandshakeInfo(handshakeInfo{
+		JwtSigningPublicKey:           "key",
+		JwtSigningPublicKeyExpiryTime: getCurrTimeInMS() - 1,
+	})
+	ResetQuerier()
+	InitQuerier("http://localhost:8080", "")
+	ConfigHandshakeStore(store)
+	if handshakeInfoInstantiated != nil {
+		t.Error("expired handshake info should not be restored")
+	}
+
+	GetQuerierInstance().apiVersion = &apiVersion
+	saveHandshakeInfo(handshakeInfo{
+		JwtSigningPublicKey:           "key",
+		JwtSigningPublicKeyExpiryTime: getCurrTimeInMS() + 10000,
+	})
+	ResetQuerier()
+	InitQuerier("http://localhost:8081", "")
+	ConfigHandshakeStore(store)
+	if handshakeInfoInstantiated != nil || GetQuerierInstance().apiVersion != nil {
+		t.Error("handshake info of other hosts should not be restored")
+	}
+}
+
+func TestFileHandshakeStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supertokens")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := NewFileHandshakeStore(filepath.Join(dir, "handshake.json"))
+
+	data, err := store.Load()
+	if err != nil || data != nil {
+		t.Error("empty store should return nil data")
+	}
+	if err = store.Save([]byte("hello")); err != nil {
+		t.Error(err)
+	}
+	data, err = store.Load()
+	if err != nil || string(data) != "hello" {
+		t.Error("saved data was not loaded")
+	}
+}
+
+type countingHandshakeStore struct {
+	HandshakeStore
+	saves int
+}
+
+func (store *countingHandshakeStore) Save(data []byte) error {
+	store.saves++
+	return store.HandshakeStore.Save(data)
+}
+
+func TestHandshakeInfoIsOnlySavedWhenTheKeyChanges(t *testing.T) {
+	resetForHandshakeStoreTest()
+	defer resetForHandshakeStoreTest()
+	InitQuerier("http://localhost:8080", "")
+	store := &countingHandshakeStore{HandshakeStore: NewInMemoryHandshakeStore()}
+	ConfigHandshakeStore(store)
+	apiVersion := "2.3"
+	GetQuerierInstance().apiVersion = &apiVersion
+
+	info := &handshakeInfo{
+		JwtSigningPublicKey:           "key",
+		JwtSigningPublicKeyExpiryTime: 1000,
+	}
+	info.UpdateJwtSigningPublicKeyInfo("key", 1000)
+	if store.saves != 0 {
+		t.Error("unchanged key was saved", store.saves)
+	}
+	info.UpdateJwtSigningPublicKeyInfo("key", 2000)
+	info.UpdateJwtSigningPublicKeyInfo("newKey", 2000)
+	info.UpdateJwtSigningPublicKeyInfo("newKey", 2000)
+	if store.saves != 2 {
+		t.Error("changed key was not saved once per change", store.saves)
+	}
+}
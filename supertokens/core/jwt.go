@@ -101,3 +101,31 @@ func getPublicKeyFromStr(str string) (*rsa.PublicKey, error) {
 
 	return pub.(*rsa.PublicKey), nil
 }
+
+// DecodeAccessTokenPayloadUnverified returns the claims of an access token WITHOUT checking its signature or
+// expiry. Only use it for tokens that have already been verified, for example by GetSession
+func DecodeAccessTokenPayloadUnverified(accessToken string) (map[string]interface{}, error) {
+	return getPayloadWithoutVerifying(accessToken)
+}
+
+// getPayloadWithoutVerifying must only be used for tokens that have already been verified, for example by the core
+func getPayloadWithoutVerifying(jwt string) (map[string]interface{}, error) {
+	var splitted = strings.Split(jwt, ".")
+	if len(splitted) != 3 {
+		return nil, errors.GeneralError{
+			Msg: "Invalid JWT",
+		}
+	}
+
+	var decodedPayload, base64Error = b64.StdEncoding.DecodeString(splitted[1])
+	if base64Error != nil {
+		return nil, base64Error
+	}
+
+	var result map[string]interface{}
+	jsonError := json.Unmarshal(decodedPayload, &result)
+	if jsonError != nil {
+		return nil, jsonError
+	}
+	return result, nil
+}
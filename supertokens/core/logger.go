@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Logger receives structured debug events as a message followed by alternating keys and values.
+// A *slog.Logger from the standard library satisfies this interface.
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+}
+
+type noopLogger struct{}
+
+func (noopLogger) Debug(msg string, keysAndValues ...interface{}) {}
+
+type standardLogger struct {
+	logger *log.Logger
+}
+
+// NewStandardLogger returns a Logger that writes events as "msg key=value ..." lines to a *log.Logger
+func NewStandardLogger(logger *log.Logger) Logger {
+	return &standardLogger{
+		logger: logger,
+	}
+}
+
+func (l *standardLogger) Debug(msg string, keysAndValues ...interface{}) {
+	var builder strings.Builder
+	builder.WriteString(msg)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		builder.WriteString(" ")
+		builder.WriteString(fmt.Sprint(keysAndValues[i]))
+		builder.WriteString("=")
+		if i+1 < len(keysAndValues) {
+			builder.WriteString(fmt.Sprint(keysAndValues[i+1]))
+		}
+	}
+	l.logger.Println(builder.String())
+}
+
+var loggerInstantiated Logger = noopLogger{}
+
+// SetLogger sets where debug events are sent. nil disables logging
+func SetLogger(logger Logger) {
+	if logger == nil {
+		logger = noopLogger{}
+	}
+	loggerInstantiated = logger
+}
+
+// LogDebug emits a debug event. Tokens must be passed through RedactToken first
+func LogDebug(msg string, keysAndValues ...interface{}) {
+	loggerInstantiated.Debug("supertokens: "+msg, keysAndValues...)
+}
+
+// RedactToken returns a short fingerprint of a token so that log lines can be correlated without leaking it
+func RedactToken(token *string) string {
+	if token == nil {
+		return "<nil>"
+	}
+	hash := sha256.Sum256([]byte(*token))
+	return "sha256:" + hex.EncodeToString(hash[:])[:12]
+}
@@ -0,0 +1,46 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestStandardLogger(t *testing.T) {
+	var buffer bytes.Buffer
+	SetLogger(NewStandardLogger(log.New(&buffer, "", 0)))
+	defer SetLogger(nil)
+
+	LogDebug("querying core", "host", "http://localhost:8080", "status", 200)
+	if buffer.String() != "supertokens: querying core host=http://localhost:8080 status=200\n" {
+		t.Error("incorrect log line", buffer.String())
+	}
+}
+
+func TestRedactToken(t *testing.T) {
+	token := "eyJhbGciOiJSUzI1NiIsInR5cCI6IkpXVCIsInZlcnNpb24iOiIyIn0="
+	redacted := RedactToken(&token)
+	if strings.Contains(redacted, token) || redacted != RedactToken(&token) {
+		t.Error("token was not redacted consistently")
+	}
+	if RedactToken(nil) != "<nil>" {
+		t.Error("nil token should be redacted as <nil>")
+	}
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"time"
+)
+
+// Ways in which a session can be verified
+const (
+	VerifiedLocally        = "local"
+	VerifiedByCore         = "core"
+	VerifiedFromCache      = "cache"
+	VerifiedInDegradedMode = "degraded"
+)
+
+// Operations that can be reported as failed
+const (
+	GetSessionOperation     = "getSession"
+	RefreshSessionOperation = "refreshSession"
+)
+
+// MetricsRecorder is called from the session and querier code paths. Implementations must be safe for concurrent use
+type MetricsRecorder interface {
+	// SessionVerified is called with one of VerifiedLocally, VerifiedByCore, VerifiedFromCache or VerifiedInDegradedMode
+	SessionVerified(method string)
+	SessionRefreshed()
+	TokenTheftDetected()
+	// OperationFailed is called with GetSessionOperation or RefreshSessionOperation
+	OperationFailed(operation string, err error)
+	// CoreRequestCompleted is called for every request to a core. statusCode is 0 if no response was received
+	CoreRequestCompleted(host string, path string, statusCode int, duration time.Duration, err error)
+}
+
+type noopMetricsRecorder struct{}
+
+func (noopMetricsRecorder) SessionVerified(method string) {}
+
+func (noopMetricsRecorder) SessionRefreshed() {}
+
+func (noopMetricsRecorder) TokenTheftDetected() {}
+
+func (noopMetricsRecorder) OperationFailed(operation string, err error) {}
+
+func (noopMetricsRecorder) CoreRequestCompleted(host string, path string, statusCode int,
+	duration time.Duration, err error) {
+}
+
+var metricsRecorderInstantiated MetricsRecorder = noopMetricsRecorder{}
+
+// SetMetricsRecorder sets where metrics are reported. nil disables metrics
+func SetMetricsRecorder(recorder MetricsRecorder) {
+	if recorder == nil {
+		recorder = noopMetricsRecorder{}
+	}
+	metricsRecorderInstantiated = recorder
+}
+
+// GetMetricsRecorder returns the configured MetricsRecorder
+func GetMetricsRecorder() MetricsRecorder {
+	return metricsRecorderInstantiated
+}
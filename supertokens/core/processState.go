@@ -74,4 +74,5 @@ func (p *processState) AddState(state int) {
 // Process states
 const (
 	CallingServiceInVerify = iota
+	AcceptedInDegradedMode
 )
@@ -18,6 +18,7 @@ package core
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
 	"io/ioutil"
@@ -25,6 +26,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/supertokens/supertokens-go/supertokens/errors"
 )
@@ -38,8 +40,16 @@ type querier struct {
 
 var querierInstantiated *querier
 var querierLock sync.Mutex
+
+// apiVersionLock guards apiVersion, which is read by every request while querierLock is held to fetch it
+var apiVersionLock sync.Mutex
+
+// hostIndexLock is separate from querierLock as that is held while fetching the API version
+var hostIndexLock sync.Mutex
 var hostsAliveForTesting = []string{}
 
+const noCoreAvailableMessage = "No SuperTokens core available to query"
+
 // ResetQuerier to be used for testing only
 func ResetQuerier() {
 	querierInstantiated = nil
@@ -95,19 +105,24 @@ func InitQuerier(hostsStr string, apiKey string) {
 
 // GetAPIVersion get's the supported CDI version
 func (querierInstance *querier) GetAPIVersion() (string, error) {
-	if querierInstance.apiVersion != nil {
-		return *(querierInstance.apiVersion), nil
+	return querierInstance.getAPIVersion(context.Background())
+}
+
+func (querierInstance *querier) getAPIVersion(ctx context.Context) (string, error) {
+	if apiVersion := querierInstance.loadAPIVersion(); apiVersion != nil {
+		return *apiVersion, nil
 	}
 	querierLock.Lock()
 	defer querierLock.Unlock()
-	if querierInstance.apiVersion != nil {
-		return *(querierInstance.apiVersion), nil
+	if apiVersion := querierInstance.loadAPIVersion(); apiVersion != nil {
+		return *apiVersion, nil
 	}
-	response, err := querierInstance.sendRequestHelper("/apiversion", func(url string) (*http.Response, error) {
-		req, err := http.NewRequest("GET", url, nil)
+	response, err := querierInstance.sendRequestHelper(ctx, "/apiversion", func(ctx context.Context, url string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 		if err != nil {
 			return nil, err
 		}
+		GetTracer().Inject(ctx, req.Header)
 		if querierInstance.apiKey != "" {
 			req.Header.Set("api-key", querierInstance.apiKey)
 		}
@@ -133,15 +148,33 @@ func (querierInstance *querier) GetAPIVersion() (string, error) {
 		}
 	}
 
-	querierInstance.apiVersion = supportedVersion
+	querierInstance.storeAPIVersion(supportedVersion)
+
+	return *supportedVersion, nil
+}
+
+func (querierInstance *querier) loadAPIVersion() *string {
+	apiVersionLock.Lock()
+	defer apiVersionLock.Unlock()
+	return querierInstance.apiVersion
+}
 
-	return *(querierInstance.apiVersion), nil
+func (querierInstance *querier) storeAPIVersion(apiVersion *string) {
+	apiVersionLock.Lock()
+	defer apiVersionLock.Unlock()
+	querierInstance.apiVersion = apiVersion
 }
 func (querierInstance *querier) GetHostsAliveForTesting() []string {
 	return hostsAliveForTesting
 }
 
 func (querierInstance *querier) SendPostRequest(requestID string, path string, data map[string]interface{}) (map[string]interface{}, error) {
+	return querierInstance.SendPostRequestWithContext(context.Background(), requestID, path, data)
+}
+
+// SendPostRequestWithContext sends a POST request to the core as part of the trace in ctx
+func (querierInstance *querier) SendPostRequestWithContext(ctx context.Context, requestID string, path string,
+	data map[string]interface{}) (map[string]interface{}, error) {
 	if path == "/session" || path == "/session/verify" || path == "/session/refresh" || path == "/handshake" {
 		data["frontendSDK"] = GetDeviceInfoInstance().GetFrontendSDKs()
 		data["drive"] = map[string]interface{}{
@@ -149,20 +182,21 @@ func (querierInstance *querier) SendPostRequest(requestID string, path string, d
 			"version": VERSION,
 		}
 	}
-	return querierInstance.sendRequestHelper(path, func(url string) (*http.Response, error) {
+	return querierInstance.sendRequestHelper(ctx, path, func(ctx context.Context, url string) (*http.Response, error) {
 		jsonData, _ := json.Marshal(data)
-		req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 		if err != nil {
 			return nil, err
 		}
 
-		apiVerion, apiVersionError := querierInstance.GetAPIVersion()
+		apiVerion, apiVersionError := querierInstance.getAPIVersion(ctx)
 		if apiVersionError != nil {
 			return nil, apiVersionError
 		}
 
 		req.Header.Set("Content-Type", "application/json")
 		req.Header.Set("cdi-version", apiVerion)
+		GetTracer().Inject(ctx, req.Header)
 		if querierInstance.apiKey != "" {
 			req.Header.Set("api-key", querierInstance.apiKey)
 		}
@@ -181,20 +215,21 @@ func getHTTPClient(requestID string) MockedHTTPClient {
 }
 
 func (querierInstance *querier) SendDeleteRequest(requestID string, path string, data map[string]interface{}) (map[string]interface{}, error) {
-	return querierInstance.sendRequestHelper(path, func(url string) (*http.Response, error) {
+	return querierInstance.sendRequestHelper(context.Background(), path, func(ctx context.Context, url string) (*http.Response, error) {
 		jsonData, _ := json.Marshal(data)
-		req, err := http.NewRequest("DELETE", url, bytes.NewBuffer(jsonData))
+		req, err := http.NewRequestWithContext(ctx, "DELETE", url, bytes.NewBuffer(jsonData))
 		if err != nil {
 			return nil, err
 		}
 
-		apiVerion, apiVersionError := querierInstance.GetAPIVersion()
+		apiVerion, apiVersionError := querierInstance.getAPIVersion(ctx)
 		if apiVersionError != nil {
 			return nil, apiVersionError
 		}
 
 		req.Header.Set("Content-Type", "application/json")
 		req.Header.Set("cdi-version", apiVerion)
+		GetTracer().Inject(ctx, req.Header)
 		if querierInstance.apiKey != "" {
 			req.Header.Set("api-key", querierInstance.apiKey)
 		}
@@ -205,8 +240,8 @@ func (querierInstance *querier) SendDeleteRequest(requestID string, path string,
 }
 
 func (querierInstance *querier) SendGetRequest(requestID string, path string, params map[string]string) (map[string]interface{}, error) {
-	return querierInstance.sendRequestHelper(path, func(url string) (*http.Response, error) {
-		req, err := http.NewRequest("GET", url, nil)
+	return querierInstance.sendRequestHelper(context.Background(), path, func(ctx context.Context, url string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 		if err != nil {
 			return nil, err
 		}
@@ -218,11 +253,12 @@ func (querierInstance *querier) SendGetRequest(requestID string, path string, pa
 		}
 		req.URL.RawQuery = q.Encode()
 
-		apiVerion, apiVersionError := querierInstance.GetAPIVersion()
+		apiVerion, apiVersionError := querierInstance.getAPIVersion(ctx)
 		if apiVersionError != nil {
 			return nil, apiVersionError
 		}
 		req.Header.Set("cdi-version", apiVerion)
+		GetTracer().Inject(ctx, req.Header)
 		if querierInstance.apiKey != "" {
 			req.Header.Set("api-key", querierInstance.apiKey)
 		}
@@ -233,20 +269,21 @@ func (querierInstance *querier) SendGetRequest(requestID string, path string, pa
 }
 
 func (querierInstance *querier) SendPutRequest(requestID string, path string, data map[string]interface{}) (map[string]interface{}, error) {
-	return querierInstance.sendRequestHelper(path, func(url string) (*http.Response, error) {
+	return querierInstance.sendRequestHelper(context.Background(), path, func(ctx context.Context, url string) (*http.Response, error) {
 		jsonData, _ := json.Marshal(data)
-		req, err := http.NewRequest("PUT", url, bytes.NewBuffer(jsonData))
+		req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewBuffer(jsonData))
 		if err != nil {
 			return nil, err
 		}
 
-		apiVerion, apiVersionError := querierInstance.GetAPIVersion()
+		apiVerion, apiVersionError := querierInstance.getAPIVersion(ctx)
 		if apiVersionError != nil {
 			return nil, apiVersionError
 		}
 
 		req.Header.Set("Content-Type", "application/json")
 		req.Header.Set("cdi-version", apiVerion)
+		GetTracer().Inject(ctx, req.Header)
 		if querierInstance.apiKey != "" {
 			req.Header.Set("api-key", querierInstance.apiKey)
 		}
@@ -256,33 +293,55 @@ func (querierInstance *querier) SendPutRequest(requestID string, path string, da
 	}, len(querierInstance.hosts))
 }
 
-type httpRequestFunction func(url string) (*http.Response, error)
+type httpRequestFunction func(ctx context.Context, url string) (*http.Response, error)
 
-func (querierInstance *querier) sendRequestHelper(path string, httpRequest httpRequestFunction,
+func (querierInstance *querier) sendRequestHelper(ctx context.Context, path string, httpRequest httpRequestFunction,
 	numberOfTries int) (map[string]interface{}, error) {
 	if numberOfTries == 0 {
+		LogDebug("no core available", "path", path)
 		return nil, errors.GeneralError{
-			Msg:         "No SuperTokens core available to query",
+			Msg:         noCoreAvailableMessage,
 			ActualError: nil,
 		}
 	}
+	hostIndexLock.Lock()
 	var currentHost = querierInstance.hosts[querierInstance.lastTriedIndex]
 	querierInstance.lastTriedIndex = (querierInstance.lastTriedIndex + 1) % len(querierInstance.hosts)
-	var resp, err = httpRequest(currentHost + path)
+	hostIndexLock.Unlock()
+	LogDebug("querying core", "host", currentHost, "path", path)
+	var spanCtx, span = GetTracer().StartSpan(ctx, CoreRequestSpanName)
+	span.SetAttribute("supertokens.core.host", currentHost)
+	span.SetAttribute("supertokens.core.path", path)
+	var startTime = time.Now()
+	var resp, err = httpRequest(spanCtx, currentHost+path)
+	var statusCode = 0
+	if resp != nil {
+		statusCode = resp.StatusCode
+		span.SetAttribute("http.status_code", statusCode)
+	}
+	if apiVersion := querierInstance.loadAPIVersion(); apiVersion != nil {
+		span.SetAttribute("supertokens.core.cdi_version", *apiVersion)
+	}
+	endSpan(span, err)
+	GetMetricsRecorder().CoreRequestCompleted(currentHost, path, statusCode, time.Since(startTime), err)
 
 	if err != nil {
 		if strings.Contains(err.Error(), "connection refused") {
-			return querierInstance.sendRequestHelper(path, httpRequest, numberOfTries-1)
+			LogDebug("core refused connection", "host", currentHost, "path", path)
+			return querierInstance.sendRequestHelper(ctx, path, httpRequest, numberOfTries-1)
 		}
 		if resp != nil {
 			resp.Body.Close()
 		}
+		LogDebug("core request failed", "host", currentHost, "path", path, "error", err.Error())
 		return nil, errors.GeneralError{
 			Msg:         err.Error(),
 			ActualError: err,
 		}
 	}
 
+	GetDegradedModeInstance().recordCoreContact()
+
 	if flag.Lookup("test.v") != nil && !containsHost(hostsAliveForTesting, currentHost) {
 		hostsAliveForTesting = append(hostsAliveForTesting, currentHost)
 	}
@@ -290,6 +349,7 @@ func (querierInstance *querier) sendRequestHelper(path string, httpRequest httpR
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
+		LogDebug("core returned an error status", "host", currentHost, "path", path, "status", resp.StatusCode)
 		return nil, errors.GeneralError{
 			Msg:         strconv.Itoa(resp.StatusCode),
 			ActualError: nil,
@@ -17,6 +17,8 @@
 package core
 
 import (
+	"context"
+
 	"github.com/supertokens/supertokens-go/supertokens/errors"
 )
 
@@ -51,7 +53,26 @@ type TokenInfo struct {
 // CreateNewSession function used to create a new SuperTokens session
 func CreateNewSession(userID string, jwtPayload map[string]interface{},
 	sessionData map[string]interface{}) (SessionInfo, error) {
-	response, err := GetQuerierInstance().SendPostRequest("newsession", "/session",
+	return CreateNewSessionWithContext(context.Background(), userID, jwtPayload, sessionData)
+}
+
+// CreateNewSessionWithContext function used to create a new SuperTokens session as part of the trace in ctx
+func CreateNewSessionWithContext(ctx context.Context, userID string, jwtPayload map[string]interface{},
+	sessionData map[string]interface{}) (session SessionInfo, err error) {
+	ctx, span := GetTracer().StartSpan(ctx, CreateNewSessionSpanName)
+	defer func() {
+		if err == nil {
+			span.SetAttribute("supertokens.session_handle", session.Handle)
+		}
+		endSpan(span, err)
+	}()
+	if GetTokenTheftPolicyInstance().isRateLimited(userID) {
+		LogDebug("new session rejected as token theft was detected for the user", "userID", userID)
+		return SessionInfo{}, errors.UnauthorizedError{
+			Msg: "new sessions are rate limited as token theft was detected for this user",
+		}
+	}
+	response, err := GetQuerierInstance().SendPostRequestWithContext(ctx, "newsession", "/session",
 		map[string]interface{}{
 			"userId":             userID,
 			"userDataInJWT":      jwtPayload,
@@ -65,10 +86,32 @@ func CreateNewSession(userID string, jwtPayload map[string]interface{},
 
 // GetSession function used to verify a session
 func GetSession(accessToken string, antiCsrfToken *string, doAntiCsrfCheck bool) (SessionInfo, error) {
+	return GetSessionWithContext(context.Background(), accessToken, antiCsrfToken, doAntiCsrfCheck)
+}
+
+// GetSessionWithContext function used to verify a session as part of the trace in ctx
+func GetSessionWithContext(ctx context.Context, accessToken string, antiCsrfToken *string,
+	doAntiCsrfCheck bool) (SessionInfo, error) {
+	ctx, span := GetTracer().StartSpan(ctx, GetSessionSpanName)
+	session, verifyMethod, err := getSession(ctx, accessToken, antiCsrfToken, doAntiCsrfCheck)
+	if err == nil {
+		span.SetAttribute("supertokens.verify_method", verifyMethod)
+		span.SetAttribute("supertokens.session_handle", session.Handle)
+	}
+	endSpan(span, err)
+	return session, err
+}
+
+// getSession also returns how the session was verified: one of VerifiedLocally, VerifiedByCore,
+// VerifiedFromCache or VerifiedInDegradedMode
+func getSession(ctx context.Context, accessToken string, antiCsrfToken *string,
+	doAntiCsrfCheck bool) (SessionInfo, string, error) {
+	var reasonForCallingCore string
 	{
 		handShakeInfo, handShakeError := GetHandshakeInfoInstance()
 		if handShakeError != nil {
-			return SessionInfo{}, handShakeError
+			GetMetricsRecorder().OperationFailed(GetSessionOperation, handShakeError)
+			return SessionInfo{}, "", handShakeError
 		}
 		if handShakeInfo.JwtSigningPublicKeyExpiryTime > getCurrTimeInMS() {
 			accessTokenInfo, accessTokenError := getInfoFromAccessToken(accessToken,
@@ -78,9 +121,12 @@ func GetSession(accessToken string, antiCsrfToken *string, doAntiCsrfCheck bool)
 					(antiCsrfToken == nil || accessTokenInfo.antiCsrfToken == nil ||
 						*antiCsrfToken != *(accessTokenInfo.antiCsrfToken)) {
 					// we continue querying the core...
+					reasonForCallingCore = "anti-csrf check failed locally"
 				} else {
 					if !handShakeInfo.AccessTokenBlacklistingEnabled &&
 						accessTokenInfo.parentRefreshTokenHash1 == nil {
+						LogDebug("session verified locally", "sessionHandle", accessTokenInfo.sessionHandle)
+						GetMetricsRecorder().SessionVerified(VerifiedLocally)
 						return SessionInfo{
 							Handle:         accessTokenInfo.sessionHandle,
 							UserID:         accessTokenInfo.userID,
@@ -89,19 +135,111 @@ func GetSession(accessToken string, antiCsrfToken *string, doAntiCsrfCheck bool)
 							RefreshToken:   nil,
 							IDRefreshToken: nil,
 							AntiCsrfToken:  nil,
-						}, nil
+						}, VerifiedLocally, nil
 					}
 					// we continue querying the core...
+					if handShakeInfo.AccessTokenBlacklistingEnabled {
+						reasonForCallingCore = "access token blacklisting is enabled"
+					} else {
+						reasonForCallingCore = "access token is from a new refresh token"
+					}
 				}
 			} else {
 				if !errors.IsTryRefreshTokenError(accessTokenError) {
-					return SessionInfo{}, accessTokenError
+					LogDebug("local session verification failed", "error", accessTokenError.Error())
+					GetMetricsRecorder().OperationFailed(GetSessionOperation, accessTokenError)
+					return SessionInfo{}, "", accessTokenError
 				}
 				// we continue querying the core...
+				reasonForCallingCore = accessTokenError.Error()
 			}
+		} else {
+			reasonForCallingCore = "jwt signing key expired"
 		}
 	}
+	LogDebug("verifying session with core", "accessToken", RedactToken(&accessToken), "reason", reasonForCallingCore)
+
+	verifyCache := GetVerifyCacheInstance()
+	key := getVerifyKey(accessToken, antiCsrfToken, doAntiCsrfCheck)
+	session, fromCache, err := verifyCache.do(ctx, key, func() (SessionInfo, error) {
+		// the result is shared with other requests, so cancelling this request must not cancel the call
+		sharedCtx, cancel := context.WithTimeout(detachContext(ctx), sharedVerifyTimeout)
+		defer cancel()
+		session, err := verifySessionWithCore(sharedCtx, accessToken, antiCsrfToken, doAntiCsrfCheck)
+		if err == nil && session.AccessToken == nil {
+			// the core has verified this token, so its expiry can be trusted
+			payload, payloadError := getPayloadWithoutVerifying(accessToken)
+			if payloadError == nil && payload["expiryTime"] != nil {
+				verifyCache.add(key, session, uint64(payload["expiryTime"].(float64)))
+			}
+		}
+		return session, err
+	})
+	if err != nil && ctx.Err() != nil {
+		// the caller gave up, which says nothing about whether the core is reachable
+		LogDebug("session verification cancelled", "accessToken", RedactToken(&accessToken), "error", ctx.Err().Error())
+		return SessionInfo{}, "", errors.GeneralError{
+			Msg:         ctx.Err().Error(),
+			ActualError: ctx.Err(),
+		}
+	}
+	if err != nil {
+		degradedSession, accepted := getSessionInDegradedMode(accessToken, antiCsrfToken, doAntiCsrfCheck, err)
+		if accepted {
+			LogDebug("session verified locally in degraded mode", "sessionHandle", degradedSession.Handle,
+				"coreError", err.Error())
+			GetMetricsRecorder().SessionVerified(VerifiedInDegradedMode)
+			return degradedSession, VerifiedInDegradedMode, nil
+		}
+		LogDebug("session verification with core failed", "accessToken", RedactToken(&accessToken), "error", err.Error())
+		GetMetricsRecorder().OperationFailed(GetSessionOperation, err)
+		return session, "", err
+	}
+	if fromCache {
+		LogDebug("session verified from cache", "sessionHandle", session.Handle)
+		GetMetricsRecorder().SessionVerified(VerifiedFromCache)
+		return session, VerifiedFromCache, nil
+	}
+	LogDebug("session verified by core", "sessionHandle", session.Handle, "newAccessToken", session.AccessToken != nil)
+	GetMetricsRecorder().SessionVerified(VerifiedByCore)
+	return session, VerifiedByCore, nil
+}
+
+// getSessionInDegradedMode verifies the access token against the last known signing key, even if
+// that key has expired, when the core could not be reached.
+func getSessionInDegradedMode(accessToken string, antiCsrfToken *string, doAntiCsrfCheck bool,
+	coreError error) (SessionInfo, bool) {
+	handShakeInfo, handShakeError := GetHandshakeInfoInstance()
+	if handShakeError != nil {
+		return SessionInfo{}, false
+	}
+	accessTokenInfo, accessTokenError := getInfoFromAccessToken(accessToken,
+		handShakeInfo.JwtSigningPublicKey, handShakeInfo.EnableAntiCsrf && doAntiCsrfCheck)
+	if accessTokenError != nil {
+		return SessionInfo{}, false
+	}
+	if handShakeInfo.EnableAntiCsrf && doAntiCsrfCheck &&
+		(antiCsrfToken == nil || accessTokenInfo.antiCsrfToken == nil ||
+			*antiCsrfToken != *(accessTokenInfo.antiCsrfToken)) {
+		return SessionInfo{}, false
+	}
+	if !GetDegradedModeInstance().accept(coreError) {
+		return SessionInfo{}, false
+	}
+	GetProcessStateInstance().AddState(AcceptedInDegradedMode)
+	return SessionInfo{
+		Handle:         accessTokenInfo.sessionHandle,
+		UserID:         accessTokenInfo.userID,
+		UserDataInJWT:  accessTokenInfo.userData,
+		AccessToken:    nil,
+		RefreshToken:   nil,
+		IDRefreshToken: nil,
+		AntiCsrfToken:  nil,
+	}, true
+}
 
+func verifySessionWithCore(ctx context.Context, accessToken string, antiCsrfToken *string,
+	doAntiCsrfCheck bool) (SessionInfo, error) {
 	GetProcessStateInstance().AddState(CallingServiceInVerify)
 
 	body := map[string]interface{}{
@@ -111,7 +249,7 @@ func GetSession(accessToken string, antiCsrfToken *string, doAntiCsrfCheck bool)
 	if antiCsrfToken != nil {
 		body["antiCsrfToken"] = *antiCsrfToken
 	}
-	response, err := GetQuerierInstance().SendPostRequest("verify", "/session/verify", body)
+	response, err := GetQuerierInstance().SendPostRequestWithContext(ctx, "verify", "/session/verify", body)
 	if err != nil {
 		return SessionInfo{}, err
 	}
@@ -138,23 +276,47 @@ func GetSession(accessToken string, antiCsrfToken *string, doAntiCsrfCheck bool)
 
 // RefreshSession function used to refresh a session
 func RefreshSession(refreshToken string, antiCsrfToken *string) (SessionInfo, error) {
+	return RefreshSessionWithContext(context.Background(), refreshToken, antiCsrfToken)
+}
+
+// RefreshSessionWithContext function used to refresh a session as part of the trace in ctx
+func RefreshSessionWithContext(ctx context.Context, refreshToken string,
+	antiCsrfToken *string) (session SessionInfo, err error) {
+	ctx, span := GetTracer().StartSpan(ctx, RefreshSessionSpanName)
+	defer func() {
+		if err == nil {
+			span.SetAttribute("supertokens.session_handle", session.Handle)
+		}
+		endSpan(span, err)
+	}()
 	body := map[string]interface{}{
 		"refreshToken": refreshToken,
 	}
 	if antiCsrfToken != nil {
 		body["antiCsrfToken"] = *antiCsrfToken
 	}
-	response, err := GetQuerierInstance().SendPostRequest("refresh", "/session/refresh", body)
+	LogDebug("refreshing session with core", "refreshToken", RedactToken(&refreshToken))
+	response, err := GetQuerierInstance().SendPostRequestWithContext(ctx, "refresh", "/session/refresh", body)
 	if err != nil {
+		LogDebug("session refresh failed", "error", err.Error())
+		GetMetricsRecorder().OperationFailed(RefreshSessionOperation, err)
 		return SessionInfo{}, err
 	}
 	if response["status"] == "OK" {
-		return convertJSONResponseToSessionInfo(response), nil
+		session := convertJSONResponseToSessionInfo(response)
+		LogDebug("session refreshed", "sessionHandle", session.Handle)
+		GetMetricsRecorder().SessionRefreshed()
+		return session, nil
 	} else if response["status"] == "UNAUTHORISED" {
-		return SessionInfo{}, errors.UnauthorizedError{
+		LogDebug("session refresh unauthorised", "message", response["message"])
+		unauthorizedError := errors.UnauthorizedError{
 			Msg: response["message"].(string),
 		}
+		GetMetricsRecorder().OperationFailed(RefreshSessionOperation, unauthorizedError)
+		return SessionInfo{}, unauthorizedError
 	} else {
+		LogDebug("token theft detected", "sessionHandle", (response["session"].(map[string]interface{}))["handle"])
+		GetMetricsRecorder().TokenTheftDetected()
 		return SessionInfo{}, errors.TokenTheftDetectedError{
 			Msg:           "Token theft detected",
 			SessionHandle: (response["session"].(map[string]interface{}))["handle"].(string),
@@ -172,8 +334,10 @@ func RevokeAllSessionsForUser(userID string) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	return convertInterfaceArrayToStringArray(
-		response["sessionHandlesRevoked"].([]interface{})), nil
+	sessionHandlesRevoked := convertInterfaceArrayToStringArray(
+		response["sessionHandlesRevoked"].([]interface{}))
+	GetVerifyCacheInstance().removeSessionHandles(sessionHandlesRevoked)
+	return sessionHandlesRevoked, nil
 }
 
 // GetAllSessionHandlesForUser function used to get all sessions for a user
@@ -198,6 +362,7 @@ func RevokeSession(sessionHandle string) (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	GetVerifyCacheInstance().removeSessionHandles([]string{sessionHandle})
 	return len(response["sessionHandlesRevoked"].([]interface{})) == 1, nil
 }
 
@@ -210,25 +375,16 @@ func RevokeMultipleSessions(sessionHandles []string) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	return convertInterfaceArrayToStringArray(
-		response["sessionHandlesRevoked"].([]interface{})), nil
+	sessionHandlesRevoked := convertInterfaceArrayToStringArray(
+		response["sessionHandlesRevoked"].([]interface{}))
+	GetVerifyCacheInstance().removeSessionHandles(sessionHandlesRevoked)
+	return sessionHandlesRevoked, nil
 }
 
 // GetSessionData function used to get session data for the given handle
 func GetSessionData(sessionHandle string) (map[string]interface{}, error) {
-	response, err := GetQuerierInstance().SendGetRequest("getsessiondata", "/session/data",
-		map[string]string{
-			"sessionHandle": sessionHandle,
-		})
-	if err != nil {
-		return nil, err
-	}
-	if response["status"] == "OK" {
-		return response["userDataInDatabase"].(map[string]interface{}), nil
-	}
-	return nil, errors.UnauthorizedError{
-		Msg: response["message"].(string),
-	}
+	sessionData, _, err := getSessionObject("getsessiondata", "/session/data", sessionHandle, "userDataInDatabase")
+	return sessionData, err
 }
 
 // UpdateSessionData function used to update session data for the given handle
@@ -251,18 +407,32 @@ func UpdateSessionData(sessionHandle string, newSessionData map[string]interface
 
 // GetJWTPayload function used to get jwt payload for the given handle
 func GetJWTPayload(sessionHandle string) (map[string]interface{}, error) {
-	response, err := GetQuerierInstance().SendGetRequest("getjwtpayload", "/jwt/data",
+	jwtPayload, _, err := getSessionObject("getjwtpayload", "/jwt/data", sessionHandle, "userDataInJWT")
+	return jwtPayload, err
+}
+
+// getSessionObject gets the object under key, and the whole response, of a GET API that takes a session handle
+func getSessionObject(apiName string, path string, sessionHandle string,
+	key string) (map[string]interface{}, map[string]interface{}, error) {
+	response, err := GetQuerierInstance().SendGetRequest(apiName, path,
 		map[string]string{
 			"sessionHandle": sessionHandle,
 		})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if response["status"] == "OK" {
-		return response["userDataInJWT"].(map[string]interface{}), nil
+		object, ok := response[key].(map[string]interface{})
+		if !ok {
+			return nil, nil, errors.GeneralError{
+				Msg: "core returned no " + key + " from " + path,
+			}
+		}
+		return object, response, nil
 	}
-	return nil, errors.UnauthorizedError{
-		Msg: response["message"].(string),
+	message, _ := response["message"].(string)
+	return nil, nil, errors.UnauthorizedError{
+		Msg: message,
 	}
 }
 
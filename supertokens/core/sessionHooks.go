@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// RequestWrapper gives access to a request, so that frameworks that are not built on net/http can be supported
+type RequestWrapper interface {
+	Context() context.Context
+	// GetHeader returns "" if the header is missing
+	GetHeader(key string) string
+	// GetCookie returns the raw value of a cookie, and false if the cookie is missing
+	GetCookie(key string) (string, bool)
+	GetMethod() string
+	GetPath() string
+	GetRemoteAddr() string
+}
+
+// SessionEvent carrier of information passed to session lifecycle hooks
+type SessionEvent struct {
+	SessionHandle string
+	// UserID is empty if a session was revoked by its handle only
+	UserID string
+	// Request is nil if the operation was not done as part of an API call, or the framework is not built on net/http
+	Request *http.Request
+	// RequestWrapper is nil if the operation was not done as part of an API call
+	RequestWrapper RequestWrapper
+	JWTPayload     map[string]interface{}
+	SessionData    map[string]interface{}
+}
+
+// Types of ImpersonationEvent
+const (
+	ImpersonationStarted = "STARTED"
+	// ImpersonationExpired is used once a session that reached its max lifetime is revoked
+	ImpersonationExpired = "EXPIRED"
+	// ImpersonationBlocked is used when a request that is not allowed while impersonating is rejected
+	ImpersonationBlocked = "BLOCKED"
+)
+
+// ImpersonationEvent carrier of information passed to the impersonation hook
+type ImpersonationEvent struct {
+	Type          string
+	SessionHandle string
+	// UserID is the user that is impersonated
+	UserID string
+	// ActorID is the user that impersonates, for example a member of the support team
+	ActorID string
+	// Request is nil if the operation was not done as part of an API call, or the framework is not built on net/http
+	Request *http.Request
+	// RequestWrapper is nil if the operation was not done as part of an API call
+	RequestWrapper RequestWrapper
+}
+
+type sessionHooks struct {
+	OnSessionCreatedHook    func(SessionEvent)
+	OnSessionRefreshedHook  func(SessionEvent)
+	OnSessionRevokedHook    func(SessionEvent)
+	OnJWTPayloadUpdatedHook func(SessionEvent)
+	OnImpersonationHook     func(ImpersonationEvent)
+}
+
+func defaultSessionHook(event SessionEvent) {}
+
+func defaultImpersonationHook(event ImpersonationEvent) {}
+
+var sessionHooksInstantiated *sessionHooks
+var sessionHooksOnce *sync.Once = new(sync.Once)
+
+// GetSessionHooksInstance returns all the session lifecycle hooks.
+func GetSessionHooksInstance() *sessionHooks {
+	sessionHooksOnce.Do(func() {
+		sessionHooksInstantiated = &sessionHooks{
+			OnSessionCreatedHook:    defaultSessionHook,
+			OnSessionRefreshedHook:  defaultSessionHook,
+			OnSessionRevokedHook:    defaultSessionHook,
+			OnJWTPayloadUpdatedHook: defaultSessionHook,
+			OnImpersonationHook:     defaultImpersonationHook,
+		}
+	})
+	return sessionHooksInstantiated
+}
+
+// ResetSessionHooks to be used for testing only
+func ResetSessionHooks() {
+	sessionHooksInstantiated = nil
+	sessionHooksOnce = new(sync.Once)
+}
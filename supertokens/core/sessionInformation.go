@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"sync"
+
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+// sessionInformationParallelism is the max number of sessions fetched at the same time by GetAllSessionsForUser
+const sessionInformationParallelism = 8
+
+// SessionInformation carrier of everything that is stored about a session. Cores up to CDI 2.3 only return the
+// session data and jwt payload of a session handle, so with them UserID is only set by GetAllSessionsForUser
+// and TimeCreated and Expiry are 0
+type SessionInformation struct {
+	Handle      string
+	UserID      string
+	SessionData map[string]interface{}
+	JWTPayload  map[string]interface{}
+	// TimeCreated and Expiry are in milliseconds
+	TimeCreated uint64
+	Expiry      uint64
+}
+
+// GetSessionInformation function used to get the user ID, session data, jwt payload and lifetime of a session
+func GetSessionInformation(sessionHandle string) (SessionInformation, error) {
+	var sessionData, sessionDataResponse, jwtPayload map[string]interface{}
+	var sessionDataError, jwtPayloadError error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sessionData, sessionDataResponse, sessionDataError = getSessionObject("getsessiondata", "/session/data",
+			sessionHandle, "userDataInDatabase")
+	}()
+	jwtPayload, jwtPayloadError = GetJWTPayload(sessionHandle)
+	wg.Wait()
+	if sessionDataError != nil {
+		return SessionInformation{}, sessionDataError
+	}
+	if jwtPayloadError != nil {
+		return SessionInformation{}, jwtPayloadError
+	}
+	// newer cores return these with the session data
+	userID, _ := sessionDataResponse["userId"].(string)
+	timeCreated, _ := sessionDataResponse["timeCreated"].(float64)
+	expiry, _ := sessionDataResponse["expiry"].(float64)
+	return SessionInformation{
+		Handle:      sessionHandle,
+		UserID:      userID,
+		SessionData: sessionData,
+		JWTPayload:  jwtPayload,
+		TimeCreated: uint64(timeCreated),
+		Expiry:      uint64(expiry),
+	}, nil
+}
+
+// GetAllSessionsForUser function used to get information about all sessions of a user. Sessions that
+// are revoked while this is running are left out
+func GetAllSessionsForUser(userID string) ([]SessionInformation, error) {
+	sessionHandles, err := GetAllSessionHandlesForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]SessionInformation, len(sessionHandles))
+	resultErrors := make([]error, len(sessionHandles))
+	semaphore := make(chan struct{}, sessionInformationParallelism)
+	var wg sync.WaitGroup
+	for i, sessionHandle := range sessionHandles {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, sessionHandle string) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			results[i], resultErrors[i] = GetSessionInformation(sessionHandle)
+		}(i, sessionHandle)
+	}
+	wg.Wait()
+
+	sessions := []SessionInformation{}
+	for i := range results {
+		if resultErrors[i] != nil {
+			if errors.IsUnauthorizedError(resultErrors[i]) {
+				continue
+			}
+			return nil, resultErrors[i]
+		}
+		results[i].UserID = userID
+		sessions = append(sessions, results[i])
+	}
+	return sessions, nil
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+func TestGetAllSessionsForUser(t *testing.T) {
+	var lock sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	handles := []string{}
+	for i := 0; i < 20; i++ {
+		handles = append(handles, "handle"+strconv.Itoa(i))
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handle := r.URL.Query().Get("sessionHandle")
+		switch r.URL.Path {
+		case "/apiversion":
+			json.NewEncoder(w).Encode(map[string]interface{}{"versions": []string{"2.3"}})
+		case "/session/user":
+			json.NewEncoder(w).Encode(map[string]interface{}{"status": "OK", "sessionHandles": handles})
+		case "/session/data", "/jwt/data":
+			lock.Lock()
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			lock.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			lock.Lock()
+			inFlight--
+			lock.Unlock()
+			if handle == "handle3" {
+				json.NewEncoder(w).Encode(map[string]interface{}{"status": "UNAUTHORISED", "message": "revoked"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"status":             "OK",
+				"userDataInDatabase": map[string]interface{}{"handle": handle},
+				"userDataInJWT":      map[string]interface{}{"role": "admin"},
+			})
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer server.Close()
+	ResetQuerier()
+	defer ResetQuerier()
+	InitQuerier(server.URL, "")
+
+	sessions, err := GetAllSessionsForUser("user1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 19 {
+		t.Fatal("revoked session should have been left out", len(sessions))
+	}
+	for _, session := range sessions {
+		if session.UserID != "user1" || session.SessionData["handle"] != session.Handle ||
+			session.JWTPayload["role"] != "admin" || session.TimeCreated != 0 {
+			t.Error("incorrect session information", session)
+		}
+	}
+	// each session makes two requests at the same time
+	if maxInFlight > 2*sessionInformationParallelism {
+		t.Error("too many requests in parallel", maxInFlight)
+	}
+}
+
+func TestGetSessionInformation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apiversion":
+			json.NewEncoder(w).Encode(map[string]interface{}{"versions": []string{"2.3"}})
+		case "/session/data":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"status":             "OK",
+				"userDataInDatabase": map[string]interface{}{"a": "b"},
+				"userId":             "user1",
+				"timeCreated":        1000,
+				"expiry":             2000,
+			})
+		case "/jwt/data":
+			json.NewEncoder(w).Encode(map[string]interface{}{"status": "OK", "userDataInJWT": map[string]interface{}{"c": "d"}})
+		}
+	}))
+	defer server.Close()
+	ResetQuerier()
+	defer ResetQuerier()
+	InitQuerier(server.URL, "")
+
+	session, err := GetSessionInformation("handle1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.Handle != "handle1" || session.UserID != "user1" || session.TimeCreated != 1000 ||
+		session.Expiry != 2000 || session.SessionData["a"] != "b" || session.JWTPayload["c"] != "d" {
+		t.Error("incorrect session information", session)
+	}
+}
+
+func TestGetSessionInformationWithInvalidResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apiversion":
+			json.NewEncoder(w).Encode(map[string]interface{}{"versions": []string{"2.3"}})
+		case "/session/data":
+			json.NewEncoder(w).Encode(map[string]interface{}{"status": "OK", "userDataInDatabase": map[string]interface{}{}})
+		default:
+			json.NewEncoder(w).Encode(map[string]interface{}{"status": "OK"})
+		}
+	}))
+	defer server.Close()
+	ResetQuerier()
+	defer ResetQuerier()
+	InitQuerier(server.URL, "")
+
+	_, err := GetSessionInformation("handle1")
+	if _, ok := err.(errors.GeneralError); !ok {
+		t.Error("missing jwt payload should be a general error", err)
+	}
+}
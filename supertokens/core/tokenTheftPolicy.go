@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+// TokenTheftEvent carrier of information about a detected token theft
+type TokenTheftEvent struct {
+	SessionHandle string
+	UserID        string
+	// RemoteAddr, ForwardedFor and UserAgent are taken from the refresh request that detected the theft
+	RemoteAddr   string
+	ForwardedFor string
+	UserAgent    string
+	DetectedTime uint64
+}
+
+// TokenTheftPolicy decides what happens after token theft is detected. It is applied in the
+// background so that the response is not delayed by requests to the core.
+type TokenTheftPolicy struct {
+	// RevokeAllSessionsForUser revokes every session of the user instead of only the affected one
+	RevokeAllSessionsForUser bool
+	// Notify is called after the sessions have been revoked, e.g. to call a webhook
+	Notify func(event TokenTheftEvent, revokeError error)
+	// RateLimitDuration rejects new sessions for the user for this long. 0 disables it
+	RateLimitDuration time.Duration
+}
+
+type tokenTheftPolicyState struct {
+	policy           *TokenTheftPolicy
+	rateLimitedUntil map[string]uint64
+}
+
+var tokenTheftPolicyInstantiated *tokenTheftPolicyState
+var tokenTheftPolicyLock sync.Mutex
+
+// GetTokenTheftPolicyInstance returns the configured token theft policy and rate limits
+func GetTokenTheftPolicyInstance() *tokenTheftPolicyState {
+	tokenTheftPolicyLock.Lock()
+	defer tokenTheftPolicyLock.Unlock()
+	if tokenTheftPolicyInstantiated == nil {
+		tokenTheftPolicyInstantiated = &tokenTheftPolicyState{
+			policy:           nil,
+			rateLimitedUntil: map[string]uint64{},
+		}
+	}
+	return tokenTheftPolicyInstantiated
+}
+
+// ConfigTokenTheftPolicy sets the policy. If nil, the default token theft handler revokes the affected session
+func ConfigTokenTheftPolicy(policy *TokenTheftPolicy) {
+	state := GetTokenTheftPolicyInstance()
+	tokenTheftPolicyLock.Lock()
+	defer tokenTheftPolicyLock.Unlock()
+	state.policy = policy
+}
+
+// ResetTokenTheftPolicy to be used for testing only
+func ResetTokenTheftPolicy() {
+	tokenTheftPolicyLock.Lock()
+	defer tokenTheftPolicyLock.Unlock()
+	tokenTheftPolicyInstantiated = nil
+}
+
+// GetPolicy returns the configured policy, or nil
+func (state *tokenTheftPolicyState) GetPolicy() *TokenTheftPolicy {
+	tokenTheftPolicyLock.Lock()
+	defer tokenTheftPolicyLock.Unlock()
+	return state.policy
+}
+
+// RateLimitUser rejects new sessions for userID for the RateLimitDuration of the policy
+func (state *tokenTheftPolicyState) RateLimitUser(userID string) {
+	tokenTheftPolicyLock.Lock()
+	defer tokenTheftPolicyLock.Unlock()
+	if state.policy == nil || state.policy.RateLimitDuration <= 0 {
+		return
+	}
+	now := getCurrTimeInMS()
+	// users whose rate limit expired without them coming back are only removed here
+	for rateLimitedUserID, until := range state.rateLimitedUntil {
+		if until <= now {
+			delete(state.rateLimitedUntil, rateLimitedUserID)
+		}
+	}
+	state.rateLimitedUntil[userID] = now + uint64(state.policy.RateLimitDuration/time.Millisecond)
+}
+
+func (state *tokenTheftPolicyState) isRateLimited(userID string) bool {
+	tokenTheftPolicyLock.Lock()
+	defer tokenTheftPolicyLock.Unlock()
+	until, ok := state.rateLimitedUntil[userID]
+	if !ok {
+		return false
+	}
+	if until <= getCurrTimeInMS() {
+		delete(state.rateLimitedUntil, userID)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenTheftRateLimit(t *testing.T) {
+	ResetTokenTheftPolicy()
+	defer ResetTokenTheftPolicy()
+	state := GetTokenTheftPolicyInstance()
+
+	state.RateLimitUser("user1")
+	if state.isRateLimited("user1") {
+		t.Error("user should not be rate limited without a policy")
+	}
+
+	ConfigTokenTheftPolicy(&TokenTheftPolicy{RateLimitDuration: 50 * time.Millisecond})
+	state.RateLimitUser("user1")
+	if !state.isRateLimited("user1") {
+		t.Error("user should be rate limited")
+	}
+	if state.isRateLimited("user2") {
+		t.Error("other users should not be rate limited")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if state.isRateLimited("user1") {
+		t.Error("rate limit should have expired")
+	}
+
+	state.RateLimitUser("user2")
+	time.Sleep(60 * time.Millisecond)
+	state.RateLimitUser("user3")
+	if _, ok := state.rateLimitedUntil["user2"]; ok || len(state.rateLimitedUntil) != 1 {
+		t.Error("expired rate limits should be pruned", state.rateLimitedUntil)
+	}
+}
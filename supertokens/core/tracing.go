@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"net/http"
+)
+
+// Span names used by this package
+const (
+	GetSessionSpanName       = "supertokens.GetSession"
+	RefreshSessionSpanName   = "supertokens.RefreshSession"
+	CreateNewSessionSpanName = "supertokens.CreateNewSession"
+	CoreRequestSpanName      = "supertokens.CoreRequest"
+)
+
+// Tracer creates spans around session operations and requests to the core. It is kept small so that
+// an OpenTelemetry tracer and propagator can be adapted to it without this package depending on them.
+type Tracer interface {
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+	// Inject adds the trace headers of ctx to a request that is sent to the core
+	Inject(ctx context.Context, header http.Header)
+}
+
+// Span is a single traced operation
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+type noopTracer struct{}
+
+func (noopTracer) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+func (noopTracer) Inject(ctx context.Context, header http.Header) {}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+
+func (noopSpan) RecordError(err error) {}
+
+func (noopSpan) End() {}
+
+var tracerInstantiated Tracer = noopTracer{}
+
+// SetTracer sets the tracer used for session operations and core requests. nil disables tracing
+func SetTracer(tracer Tracer) {
+	if tracer == nil {
+		tracer = noopTracer{}
+	}
+	tracerInstantiated = tracer
+}
+
+// GetTracer returns the configured Tracer
+func GetTracer() Tracer {
+	return tracerInstantiated
+}
+
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type recordedSpan struct {
+	name       string
+	parent     string
+	attributes map[string]interface{}
+	ended      bool
+}
+
+type recordingTracer struct {
+	lock  sync.Mutex
+	spans []*recordedSpan
+}
+
+type spanNameKey struct{}
+
+func (tracer *recordingTracer) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	tracer.lock.Lock()
+	defer tracer.lock.Unlock()
+	parent, _ := ctx.Value(spanNameKey{}).(string)
+	span := &recordedSpan{name: name, parent: parent, attributes: map[string]interface{}{}}
+	tracer.spans = append(tracer.spans, span)
+	return context.WithValue(ctx, spanNameKey{}, name), span
+}
+
+func (tracer *recordingTracer) Inject(ctx context.Context, header http.Header) {
+	parent, _ := ctx.Value(spanNameKey{}).(string)
+	header.Set("traceparent", parent)
+}
+
+func (span *recordedSpan) SetAttribute(key string, value interface{}) {
+	span.attributes[key] = value
+}
+
+func (span *recordedSpan) RecordError(err error) {
+	span.attributes["error"] = err
+}
+
+func (span *recordedSpan) End() {
+	span.ended = true
+}
+
+func TestCoreRequestsAreTraced(t *testing.T) {
+	traceparents := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents[r.URL.Path] = r.Header.Get("traceparent")
+		if r.URL.Path == "/apiversion" {
+			w.Write([]byte(`{"versions":["2.3"]}`))
+			return
+		}
+		w.Write([]byte(`{"status":"OK"}`))
+	}))
+	defer server.Close()
+
+	ResetQuerier()
+	defer ResetQuerier()
+	tracer := &recordingTracer{}
+	SetTracer(tracer)
+	defer SetTracer(nil)
+	InitQuerier(server.URL, "")
+
+	ctx, span := GetTracer().StartSpan(context.Background(), "request")
+	_, err := GetQuerierInstance().SendPostRequestWithContext(ctx, "", "/session/remove", map[string]interface{}{})
+	span.End()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if traceparents["/apiversion"] != CoreRequestSpanName || traceparents["/session/remove"] != CoreRequestSpanName {
+		t.Error("trace headers were not sent to the core", traceparents)
+	}
+	if len(tracer.spans) != 3 {
+		t.Fatal("expected a span for the request and one for each core request", len(tracer.spans))
+	}
+	removeSpan, apiVersionSpan := tracer.spans[1], tracer.spans[2]
+	if removeSpan.name != CoreRequestSpanName || removeSpan.parent != "request" || !removeSpan.ended ||
+		removeSpan.attributes["supertokens.core.path"] != "/session/remove" ||
+		removeSpan.attributes["supertokens.core.host"] != server.URL ||
+		removeSpan.attributes["supertokens.core.cdi_version"] != "2.3" ||
+		removeSpan.attributes["http.status_code"] != 200 {
+		t.Error("incorrect core request span", removeSpan)
+	}
+	// the CDI version is fetched while sending the first request
+	if apiVersionSpan.parent != CoreRequestSpanName || !apiVersionSpan.ended ||
+		apiVersionSpan.attributes["supertokens.core.path"] != "/apiversion" {
+		t.Error("incorrect api version span", apiVersionSpan)
+	}
+}
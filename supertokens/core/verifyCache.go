@@ -0,0 +1,244 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+// sharedVerifyTimeout limits a /session/verify call whose result is shared between requests
+const sharedVerifyTimeout = 10 * time.Second
+
+// detachedContext keeps the values of its parent, such as the trace span, but not its deadline or
+// cancellation
+type detachedContext struct {
+	parent context.Context
+}
+
+func detachContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return detachedContext{parent: ctx}
+}
+
+func (ctx detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (ctx detachedContext) Done() <-chan struct{} { return nil }
+
+func (ctx detachedContext) Err() error { return nil }
+
+func (ctx detachedContext) Value(key interface{}) interface{} { return ctx.parent.Value(key) }
+
+// verifyCall is a /session/verify request that is currently in flight
+type verifyCall struct {
+	done   chan struct{}
+	result SessionInfo
+	err    error
+}
+
+type verifyCacheEntry struct {
+	key           string
+	sessionHandle string
+	result        SessionInfo
+	expiry        uint64
+}
+
+type verifyCache struct {
+	inFlight map[string]*verifyCall
+	entries  map[string]*list.Element
+	order    *list.List
+	maxSize  int
+	maxAge   uint64
+}
+
+var verifyCacheInstantiated *verifyCache
+var verifyCacheLock sync.Mutex
+
+// GetVerifyCacheInstance returns the struct used to deduplicate and cache calls to /session/verify
+func GetVerifyCacheInstance() *verifyCache {
+	verifyCacheLock.Lock()
+	defer verifyCacheLock.Unlock()
+	if verifyCacheInstantiated == nil {
+		verifyCacheInstantiated = &verifyCache{
+			inFlight: map[string]*verifyCall{},
+			entries:  map[string]*list.Element{},
+			order:    list.New(),
+			maxSize:  0,
+			maxAge:   0,
+		}
+	}
+	return verifyCacheInstantiated
+}
+
+// ConfigVerifyCache enables caching of successful verify results. A maxSize or maxAge of 0 disables it.
+func ConfigVerifyCache(maxSize int, maxAgeInMS uint64) {
+	cache := GetVerifyCacheInstance()
+	verifyCacheLock.Lock()
+	defer verifyCacheLock.Unlock()
+	cache.maxSize = maxSize
+	cache.maxAge = maxAgeInMS
+	cache.entries = map[string]*list.Element{}
+	cache.order = list.New()
+}
+
+// ResetVerifyCache to be used for testing only
+func ResetVerifyCache() {
+	verifyCacheLock.Lock()
+	defer verifyCacheLock.Unlock()
+	verifyCacheInstantiated = nil
+}
+
+func getVerifyKey(accessToken string, antiCsrfToken *string, doAntiCsrfCheck bool) string {
+	key := accessToken + ";" + strconv.FormatBool(doAntiCsrfCheck)
+	if antiCsrfToken != nil {
+		key = key + ";" + *antiCsrfToken
+	}
+	return key
+}
+
+// do runs verify once for all concurrent callers that use the same key. If caching is
+// enabled, a successful result is also served from memory until the token or the entry expires.
+// Callers other than the one that ran verify get a copy, so that changing the JWT payload of one
+// request does not change it for another. A caller that waits for another caller's verify stops
+// waiting once ctx is done.
+func (cache *verifyCache) do(ctx context.Context, key string,
+	verify func() (SessionInfo, error)) (SessionInfo, bool, error) {
+	verifyCacheLock.Lock()
+	if element, ok := cache.entries[key]; ok {
+		entry := element.Value.(*verifyCacheEntry)
+		if entry.expiry > getCurrTimeInMS() {
+			cache.order.MoveToFront(element)
+			verifyCacheLock.Unlock()
+			return copySessionInfo(entry.result), true, nil
+		}
+		cache.removeElement(element)
+	}
+	if call, ok := cache.inFlight[key]; ok {
+		verifyCacheLock.Unlock()
+		select {
+		case <-call.done:
+			return copySessionInfo(call.result), false, call.err
+		case <-ctx.Done():
+			return SessionInfo{}, false, errors.GeneralError{
+				Msg:         ctx.Err().Error(),
+				ActualError: ctx.Err(),
+			}
+		}
+	}
+	call := &verifyCall{
+		done: make(chan struct{}),
+		// returned to the waiters if verify panics
+		err: errors.GeneralError{
+			Msg: "session verification was aborted",
+		},
+	}
+	cache.inFlight[key] = call
+	verifyCacheLock.Unlock()
+
+	defer func() {
+		verifyCacheLock.Lock()
+		delete(cache.inFlight, key)
+		verifyCacheLock.Unlock()
+		close(call.done)
+	}()
+	call.result, call.err = verify()
+
+	return call.result, false, call.err
+}
+
+// add caches a successful verify result for at most maxAge and never past tokenExpiry
+func (cache *verifyCache) add(key string, result SessionInfo, tokenExpiry uint64) {
+	verifyCacheLock.Lock()
+	defer verifyCacheLock.Unlock()
+	if cache.maxSize <= 0 || cache.maxAge == 0 {
+		return
+	}
+	expiry := getCurrTimeInMS() + cache.maxAge
+	if tokenExpiry < expiry {
+		expiry = tokenExpiry
+	}
+	if element, ok := cache.entries[key]; ok {
+		cache.removeElement(element)
+	}
+	cache.entries[key] = cache.order.PushFront(&verifyCacheEntry{
+		key:           key,
+		sessionHandle: result.Handle,
+		result:        copySessionInfo(result),
+		expiry:        expiry,
+	})
+	for cache.order.Len() > cache.maxSize {
+		cache.removeElement(cache.order.Back())
+	}
+}
+
+// removeSessionHandles drops cached results of sessions that have been revoked
+func (cache *verifyCache) removeSessionHandles(sessionHandles []string) {
+	verifyCacheLock.Lock()
+	defer verifyCacheLock.Unlock()
+	if len(cache.entries) == 0 {
+		return
+	}
+	for element := cache.order.Front(); element != nil; {
+		next := element.Next()
+		entry := element.Value.(*verifyCacheEntry)
+		for _, handle := range sessionHandles {
+			if entry.sessionHandle == handle {
+				cache.removeElement(element)
+				break
+			}
+		}
+		element = next
+	}
+}
+
+func (cache *verifyCache) removeElement(element *list.Element) {
+	entry := cache.order.Remove(element).(*verifyCacheEntry)
+	delete(cache.entries, entry.key)
+}
+
+// copySessionInfo copies the JWT payload, which is the only part of a SessionInfo that callers change
+func copySessionInfo(info SessionInfo) SessionInfo {
+	if info.UserDataInJWT != nil {
+		info.UserDataInJWT = copyJSONValue(info.UserDataInJWT).(map[string]interface{})
+	}
+	return info
+}
+
+func copyJSONValue(value interface{}) interface{} {
+	switch actual := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(actual))
+		for key, item := range actual {
+			result[key] = copyJSONValue(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(actual))
+		for index, item := range actual {
+			result[index] = copyJSONValue(item)
+		}
+		return result
+	}
+	return value
+}
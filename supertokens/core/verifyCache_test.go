@@ -0,0 +1,223 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConcurrentVerifyCallsAreDeduplicated(t *testing.T) {
+	ResetVerifyCache()
+	defer ResetVerifyCache()
+	cache := GetVerifyCacheInstance()
+
+	var calls int32
+	release := make(chan struct{})
+	verify := func() (SessionInfo, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return SessionInfo{Handle: "handle"}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, _, err := cache.do(context.Background(), "key", verify)
+			if err != nil || result.Handle != "handle" {
+				t.Error("incorrect result")
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Error("verify was called more than once")
+	}
+}
+
+func TestVerifyCacheDisabledByDefault(t *testing.T) {
+	ResetVerifyCache()
+	defer ResetVerifyCache()
+	cache := GetVerifyCacheInstance()
+
+	cache.add("key", SessionInfo{Handle: "handle"}, getCurrTimeInMS()+10000)
+	calls := 0
+	cache.do(context.Background(), "key", func() (SessionInfo, error) {
+		calls++
+		return SessionInfo{}, nil
+	})
+	if calls != 1 {
+		t.Error("result should not have been cached")
+	}
+}
+
+func TestVerifyCacheExpiryAndEviction(t *testing.T) {
+	ResetVerifyCache()
+	defer ResetVerifyCache()
+	ConfigVerifyCache(2, 10000)
+	cache := GetVerifyCacheInstance()
+
+	calls := 0
+	verify := func() (SessionInfo, error) {
+		calls++
+		return SessionInfo{}, nil
+	}
+
+	cache.add("expired", SessionInfo{Handle: "h1"}, getCurrTimeInMS()-1)
+	cache.do(context.Background(), "expired", verify)
+	if calls != 1 {
+		t.Error("expired token should not be served from cache")
+	}
+
+	cache.add("a", SessionInfo{Handle: "h1"}, getCurrTimeInMS()+10000)
+	cache.add("b", SessionInfo{Handle: "h2"}, getCurrTimeInMS()+10000)
+	cache.add("c", SessionInfo{Handle: "h3"}, getCurrTimeInMS()+10000)
+
+	result, fromCache, _ := cache.do(context.Background(), "c", verify)
+	if calls != 1 || !fromCache || result.Handle != "h3" {
+		t.Error("c should have been served from cache")
+	}
+	cache.do(context.Background(), "a", verify)
+	if calls != 2 {
+		t.Error("a should have been evicted")
+	}
+
+	cache.removeSessionHandles([]string{"h3"})
+	cache.do(context.Background(), "c", verify)
+	if calls != 3 {
+		t.Error("revoked session should have been removed from cache")
+	}
+}
+
+func TestWaitersAreReleasedIfVerifyPanics(t *testing.T) {
+	ResetVerifyCache()
+	defer ResetVerifyCache()
+	cache := GetVerifyCacheInstance()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("panic of verify was not passed on")
+			}
+		}()
+		cache.do(context.Background(), "key", func() (SessionInfo, error) {
+			close(started)
+			<-release
+			panic("verify failed")
+		})
+	}()
+	<-started
+	done := make(chan error)
+	go func() {
+		_, _, err := cache.do(context.Background(), "key", func() (SessionInfo, error) {
+			return SessionInfo{}, nil
+		})
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("waiter did not get an error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiter is still blocked")
+	}
+	if _, _, err := cache.do(context.Background(), "key", func() (SessionInfo, error) {
+		return SessionInfo{Handle: "handle"}, nil
+	}); err != nil {
+		t.Error("in flight call was not removed", err)
+	}
+}
+
+func TestCachedPayloadIsCopied(t *testing.T) {
+	ResetVerifyCache()
+	defer ResetVerifyCache()
+	ConfigVerifyCache(2, 10000)
+	cache := GetVerifyCacheInstance()
+
+	payload := map[string]interface{}{"roles": []interface{}{"admin"}}
+	cache.add("key", SessionInfo{Handle: "handle", UserDataInJWT: payload}, getCurrTimeInMS()+10000)
+	payload["roles"].([]interface{})[0] = "changed"
+
+	first, fromCache, _ := cache.do(context.Background(), "key", nil)
+	if !fromCache || first.UserDataInJWT["roles"].([]interface{})[0] != "admin" {
+		t.Error("cached payload was changed by the caller", first.UserDataInJWT)
+	}
+	first.UserDataInJWT["roles"] = "changed"
+	second, _, _ := cache.do(context.Background(), "key", nil)
+	if second.UserDataInJWT["roles"].([]interface{})[0] != "admin" {
+		t.Error("cached payload was changed by another request", second.UserDataInJWT)
+	}
+}
+
+func TestWaiterStopsWaitingWhenCancelled(t *testing.T) {
+	ResetVerifyCache()
+	defer ResetVerifyCache()
+	cache := GetVerifyCacheInstance()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := cache.do(context.Background(), "key", func() (SessionInfo, error) {
+			close(started)
+			<-release
+			return SessionInfo{Handle: "handle"}, nil
+		})
+		done <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := cache.do(ctx, "key", nil); err == nil {
+		t.Error("cancelled waiter did not get an error")
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Error("cancelling a waiter failed the shared call", err)
+	}
+}
+
+type detachTestKey struct{}
+
+func TestDetachedContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), detachTestKey{}, "span"))
+	cancel()
+	detached := detachContext(ctx)
+	if detached.Err() != nil || detached.Done() != nil {
+		t.Error("detached context was cancelled with its parent")
+	}
+	if _, ok := detached.Deadline(); ok {
+		t.Error("detached context has a deadline")
+	}
+	if detached.Value(detachTestKey{}) != "span" {
+		t.Error("detached context lost the values of its parent")
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"net"
+	"strings"
+)
+
+// DeviceMetadataKey is the key in session data under which device metadata is stored. Keep it
+// when replacing the session data with UpdateSessionData, or the metadata is lost
+const DeviceMetadataKey = "st-device"
+
+// DeviceMetadata carrier of information about the device a session was created on
+type DeviceMetadata struct {
+	UserAgent string
+	IPAddress string
+	// Label is a human readable description such as "Chrome on macOS"
+	Label string
+	// Location is left empty by DefaultDeviceMetadataExtractor
+	Location string
+}
+
+// DefaultDeviceMetadataExtractor function used to get device metadata from the user agent and remote
+// address of a request. Use a custom extractor if the app is behind a proxy
+func DefaultDeviceMetadataExtractor(request RequestWrapper) DeviceMetadata {
+	ipAddress, _, err := net.SplitHostPort(request.GetRemoteAddr())
+	if err != nil {
+		ipAddress = request.GetRemoteAddr()
+	}
+	userAgent := request.GetHeader("User-Agent")
+	return DeviceMetadata{
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+		Label:     getDeviceLabel(userAgent),
+	}
+}
+
+// GetDeviceMetadataFromSessionData function used to read device metadata from the session data of a
+// session. Returns nil if none was stored
+func GetDeviceMetadataFromSessionData(sessionData map[string]interface{}) *DeviceMetadata {
+	value, ok := sessionData[DeviceMetadataKey].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	getString := func(key string) string {
+		result, _ := value[key].(string)
+		return result
+	}
+	return &DeviceMetadata{
+		UserAgent: getString("userAgent"),
+		IPAddress: getString("ipAddress"),
+		Label:     getString("label"),
+		Location:  getString("location"),
+	}
+}
+
+// addDeviceMetadataToSessionData returns a copy of sessionData with the device metadata of request
+func addDeviceMetadataToSessionData(sessionData map[string]interface{},
+	request RequestWrapper) map[string]interface{} {
+	if configMap == nil || configMap.DeviceMetadataExtractor == nil || request == nil {
+		return sessionData
+	}
+	metadata := configMap.DeviceMetadataExtractor(request)
+	result := map[string]interface{}{}
+	for key, value := range sessionData {
+		result[key] = value
+	}
+	result[DeviceMetadataKey] = map[string]interface{}{
+		"userAgent": metadata.UserAgent,
+		"ipAddress": metadata.IPAddress,
+		"label":     metadata.Label,
+		"location":  metadata.Location,
+	}
+	return result
+}
+
+func getDeviceLabel(userAgent string) string {
+	browser := "Unknown browser"
+	switch {
+	case strings.Contains(userAgent, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(userAgent, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(userAgent, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(userAgent, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(userAgent, "Safari/"):
+		browser = "Safari"
+	}
+	operatingSystem := "unknown OS"
+	switch {
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"):
+		operatingSystem = "iOS"
+	case strings.Contains(userAgent, "Android"):
+		operatingSystem = "Android"
+	case strings.Contains(userAgent, "Windows"):
+		operatingSystem = "Windows"
+	case strings.Contains(userAgent, "Mac OS X"):
+		operatingSystem = "macOS"
+	case strings.Contains(userAgent, "Linux"):
+		operatingSystem = "Linux"
+	}
+	return browser + " on " + operatingSystem
+}
@@ -57,6 +57,52 @@ func (err UnauthorizedError) Error() string {
 	return err.Msg
 }
 
+// PartialFailureError used for when an operation on many users or sessions failed for some of them
+type PartialFailureError struct {
+	Msg string
+	// Errors maps each user ID or session handle for which the operation failed to its error
+	Errors map[string]error
+}
+
+func (err PartialFailureError) Error() string {
+	return err.Msg
+}
+
+// ForbiddenError used for when the session is valid but its claims do not allow the request
+type ForbiddenError struct {
+	Msg string
+}
+
+func (err ForbiddenError) Error() string {
+	return err.Msg
+}
+
+// InvalidClaimError used for when a claim in the JWT payload of a session fails its validator
+type InvalidClaimError struct {
+	Msg string
+	// ClaimKey is the key of the claim in the JWT payload
+	ClaimKey string
+	// Reason is the message of the error returned by the validator
+	Reason string
+}
+
+func (err InvalidClaimError) Error() string {
+	return err.Msg
+}
+
+// ReauthenticationRequiredError used for when the user has to authenticate again for a sensitive request
+type ReauthenticationRequiredError struct {
+	Msg string
+	// MaxAge is the longest time in milliseconds since the last authentication that is accepted
+	MaxAge uint64
+	// MinAssuranceLevel is the lowest assurance level that is accepted
+	MinAssuranceLevel int
+}
+
+func (err ReauthenticationRequiredError) Error() string {
+	return err.Msg
+}
+
 // IsTokenTheftDetectedError returns true if error is a TokenTheftDetectedError
 func IsTokenTheftDetectedError(err error) bool {
 	return reflect.TypeOf(err) == reflect.TypeOf(TokenTheftDetectedError{})
@@ -71,3 +117,23 @@ func IsUnauthorizedError(err error) bool {
 func IsTryRefreshTokenError(err error) bool {
 	return reflect.TypeOf(err) == reflect.TypeOf(TryRefreshTokenError{})
 }
+
+// IsPartialFailureError returns true if error is a PartialFailureError
+func IsPartialFailureError(err error) bool {
+	return reflect.TypeOf(err) == reflect.TypeOf(PartialFailureError{})
+}
+
+// IsForbiddenError returns true if error is a ForbiddenError
+func IsForbiddenError(err error) bool {
+	return reflect.TypeOf(err) == reflect.TypeOf(ForbiddenError{})
+}
+
+// IsInvalidClaimError returns true if error is a InvalidClaimError
+func IsInvalidClaimError(err error) bool {
+	return reflect.TypeOf(err) == reflect.TypeOf(InvalidClaimError{})
+}
+
+// IsReauthenticationRequiredError returns true if error is a ReauthenticationRequiredError
+func IsReauthenticationRequiredError(err error) bool {
+	return reflect.TypeOf(err) == reflect.TypeOf(ReauthenticationRequiredError{})
+}
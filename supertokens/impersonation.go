@@ -0,0 +1,211 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/supertokens/supertokens-go/supertokens/core"
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+// ActorIDKey is the key in the JWT payload of impersonation sessions under which the impersonating user is stored.
+// It and ImpersonationExpiryKey are kept when the payload is replaced or merged, cannot be changed, and cannot be
+// given to CreateNewSession
+const ActorIDKey = "actorId"
+
+// ImpersonationExpiryKey is the key in the JWT payload of impersonation sessions of the time in milliseconds after
+// which they are revoked
+const ImpersonationExpiryKey = "impersonationExpiry"
+
+// DefaultImpersonationMaxLifetime is used if ImpersonationMaxLifetime is not set
+const DefaultImpersonationMaxLifetime = time.Hour
+
+// CreateImpersonationSession function used to create a session for targetUserID that is used by actorUserID, for
+// example a member of the support team. It is revoked once ImpersonationMaxLifetime has passed
+func CreateImpersonationSession(response http.ResponseWriter, targetUserID string, actorUserID string,
+	payload ...map[string]interface{}) (Session, error) {
+	return createImpersonationSession(context.Background(), wrapResponse(response), nil, targetUserID, actorUserID,
+		payload...)
+}
+
+// CreateImpersonationSessionWithRequest function used to create an impersonation session while handling request
+func CreateImpersonationSessionWithRequest(response http.ResponseWriter, request *http.Request, targetUserID string,
+	actorUserID string, payload ...map[string]interface{}) (Session, error) {
+	return createImpersonationSession(request.Context(), wrapResponse(response), wrapRequest(request), targetUserID, actorUserID,
+		payload...)
+}
+
+// CreateImpersonationSessionWithWrapper function used to create an impersonation session for frameworks that are
+// not built on net/http
+func CreateImpersonationSessionWithWrapper(response ResponseWrapper, request RequestWrapper, targetUserID string,
+	actorUserID string, payload ...map[string]interface{}) (Session, error) {
+	return createImpersonationSession(request.Context(), response, request, targetUserID, actorUserID, payload...)
+}
+
+func createImpersonationSession(ctx context.Context, response ResponseWrapper, request RequestWrapper,
+	targetUserID string, actorUserID string, payload ...map[string]interface{}) (Session, error) {
+	if actorUserID == "" || actorUserID == targetUserID {
+		return Session{}, errors.GeneralError{
+			Msg: "the actor of an impersonation session has to be another user",
+		}
+	}
+	maxLifetime := DefaultImpersonationMaxLifetime
+	if configMap != nil && configMap.ImpersonationMaxLifetime > 0 {
+		maxLifetime = configMap.ImpersonationMaxLifetime
+	}
+	jwtPayload := map[string]interface{}{}
+	var sessionData map[string]interface{}
+	if len(payload) != 0 {
+		for key, value := range payload[0] {
+			jwtPayload[key] = value
+		}
+	}
+	if len(payload) == 2 {
+		sessionData = payload[1]
+	}
+	jwtPayload[ActorIDKey] = actorUserID
+	jwtPayload[ImpersonationExpiryKey] = getCurrentTimeInMS() + uint64(maxLifetime/time.Millisecond)
+
+	session, err := startSession(ctx, response, request, targetUserID, jwtPayload, sessionData)
+	if err != nil {
+		return Session{}, err
+	}
+	core.LogDebug("impersonation session created", "userID", targetUserID, "actorID", actorUserID)
+	core.GetSessionHooksInstance().OnImpersonationHook(core.ImpersonationEvent{
+		Type:           core.ImpersonationStarted,
+		SessionHandle:  session.sessionHandle,
+		UserID:         session.userID,
+		ActorID:        actorUserID,
+		Request:        getHTTPRequest(request),
+		RequestWrapper: request,
+	})
+	return session, nil
+}
+
+// IsImpersonated function returns true if this session was created with CreateImpersonationSession
+func (session *Session) IsImpersonated() bool {
+	return session.GetActorID() != ""
+}
+
+// GetActorID function gets the user that impersonates the user of this session. Empty if it is not impersonated
+func (session *Session) GetActorID() string {
+	actorID, _ := session.GetJWTPayload()[ActorIDKey].(string)
+	return actorID
+}
+
+// revokeExpiredImpersonation revokes an impersonation session whose max lifetime has passed and returns the
+// UnauthorizedError to respond with. It returns nil for all other sessions
+func revokeExpiredImpersonation(response ResponseWrapper, request RequestWrapper, sessionHandle string,
+	userID string, jwtPayload map[string]interface{}) error {
+	actorID, _ := jwtPayload[ActorIDKey].(string)
+	if actorID == "" {
+		return nil
+	}
+	// a missing or invalid expiry counts as expired
+	expiry, ok := jwtPayload[ImpersonationExpiryKey].(float64)
+	if ok && expiry >= 0 && getCurrentTimeInMS() <= uint64(expiry) {
+		return nil
+	}
+	core.LogDebug("impersonation session expired", "userID", userID, "actorID", actorID)
+	if _, err := revokeSession(sessionHandle, userID, request); err != nil {
+		return err
+	}
+	core.GetSessionHooksInstance().OnImpersonationHook(core.ImpersonationEvent{
+		Type:           core.ImpersonationExpired,
+		SessionHandle:  sessionHandle,
+		UserID:         userID,
+		ActorID:        actorID,
+		Request:        getHTTPRequest(request),
+		RequestWrapper: request,
+	})
+	handShakeInfo, handshakeInfoError := core.GetHandshakeInfoInstance()
+	if handshakeInfoError != nil {
+		return handshakeInfoError
+	}
+	clearSessionFromCookie(response,
+		handShakeInfo.CookieDomain,
+		handShakeInfo.CookieSecure,
+		handShakeInfo.AccessTokenPath,
+		handShakeInfo.RefreshTokenPath,
+		handShakeInfo.IDRefreshTokenPath,
+		handShakeInfo.CookieSameSite,
+	)
+	return errors.UnauthorizedError{
+		Msg: "impersonation session has expired",
+	}
+}
+
+// keepImpersonation returns newJWTPayload with the impersonation keys of currentJWTPayload, so that an
+// impersonation session cannot be turned into a normal one, or a normal one into an impersonation session,
+// by updating its payload
+func keepImpersonation(currentJWTPayload map[string]interface{},
+	newJWTPayload map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for key, value := range newJWTPayload {
+		result[key] = value
+	}
+	for _, key := range []string{ActorIDKey, ImpersonationExpiryKey} {
+		delete(result, key)
+		if value, ok := currentJWTPayload[key]; ok {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// CheckNotImpersonated function used to get a ForbiddenError if session is an impersonation session. The
+// impersonation hook is called for rejected sessions
+func CheckNotImpersonated(session Session, request *http.Request) error {
+	if !session.IsImpersonated() {
+		return nil
+	}
+	core.GetSessionHooksInstance().OnImpersonationHook(core.ImpersonationEvent{
+		Type:           core.ImpersonationBlocked,
+		SessionHandle:  session.sessionHandle,
+		UserID:         session.userID,
+		ActorID:        session.GetActorID(),
+		Request:        request,
+		RequestWrapper: wrapRequestIfPresent(request),
+	})
+	return errors.ForbiddenError{
+		Msg: "this request is not allowed while impersonating a user",
+	}
+}
+
+// BlockImpersonation returns a middleware that responds with a ForbiddenError for impersonation sessions, for
+// routes such as changing the password. It has to run after the session middleware
+func BlockImpersonation() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			session := GetSessionFromRequest(r)
+			if session == nil {
+				HandleErrorAndRespond(errors.UnauthorizedError{
+					Msg: "no session found for this request",
+				}, w)
+				return
+			}
+			if err := CheckNotImpersonated(*session, r); err != nil {
+				HandleErrorAndRespond(err, w)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
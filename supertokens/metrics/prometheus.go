@@ -0,0 +1,221 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/supertokens/supertokens-go/supertokens/core"
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+var _ core.MetricsRecorder = &PrometheusRecorder{}
+
+// durationBuckets are the upper bounds, in seconds, of the core request latency histogram
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// PrometheusRecorder is a MetricsRecorder that serves what it records in the Prometheus text
+// exposition format. Mount it as an http.Handler, for example on /metrics
+type PrometheusRecorder struct {
+	lock                 sync.Mutex
+	sessionVerifications map[string]uint64
+	sessionRefreshes     uint64
+	tokenTheftDetections uint64
+	operationErrors      map[[2]string]uint64
+	coreRequests         map[[3]string]uint64
+	coreRequestDurations map[string]*histogram
+}
+
+// NewPrometheusRecorder returns an empty PrometheusRecorder
+func NewPrometheusRecorder() *PrometheusRecorder {
+	return &PrometheusRecorder{
+		sessionVerifications: map[string]uint64{},
+		operationErrors:      map[[2]string]uint64{},
+		coreRequests:         map[[3]string]uint64{},
+		coreRequestDurations: map[string]*histogram{},
+	}
+}
+
+// SessionVerified counts verified sessions by how they were verified
+func (recorder *PrometheusRecorder) SessionVerified(method string) {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	recorder.sessionVerifications[method]++
+}
+
+// SessionRefreshed counts refreshed sessions
+func (recorder *PrometheusRecorder) SessionRefreshed() {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	recorder.sessionRefreshes++
+}
+
+// TokenTheftDetected counts detected token thefts
+func (recorder *PrometheusRecorder) TokenTheftDetected() {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	recorder.tokenTheftDetections++
+}
+
+// OperationFailed counts failed operations by type of error
+func (recorder *PrometheusRecorder) OperationFailed(operation string, err error) {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	recorder.operationErrors[[2]string{operation, getErrorType(err)}]++
+}
+
+// CoreRequestCompleted counts requests to each core and observes their latency
+func (recorder *PrometheusRecorder) CoreRequestCompleted(host string, path string, statusCode int,
+	duration time.Duration, err error) {
+	status := strconv.Itoa(statusCode)
+	if err != nil && statusCode == 0 {
+		status = "error"
+	}
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	recorder.coreRequests[[3]string{host, path, status}]++
+	durationHistogram := recorder.coreRequestDurations[host]
+	if durationHistogram == nil {
+		durationHistogram = &histogram{
+			counts: make([]uint64, len(durationBuckets)),
+		}
+		recorder.coreRequestDurations[host] = durationHistogram
+	}
+	seconds := duration.Seconds()
+	for i, bucket := range durationBuckets {
+		if seconds <= bucket {
+			durationHistogram.counts[i]++
+		}
+	}
+	durationHistogram.sum += seconds
+	durationHistogram.count++
+}
+
+// ServeHTTP writes all metrics in the Prometheus text exposition format
+func (recorder *PrometheusRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	recorder.WriteTo(w)
+}
+
+// WriteTo writes all metrics in the Prometheus text exposition format
+func (recorder *PrometheusRecorder) WriteTo(w io.Writer) (int64, error) {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	var builder strings.Builder
+
+	writeHeader(&builder, "supertokens_session_verifications_total", "counter",
+		"Sessions verified, by how they were verified.")
+	methods := []string{}
+	for method := range recorder.sessionVerifications {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		fmt.Fprintf(&builder, "supertokens_session_verifications_total{method=\"%s\"} %d\n",
+			escapeLabelValue(method), recorder.sessionVerifications[method])
+	}
+
+	writeHeader(&builder, "supertokens_session_refreshes_total", "counter", "Sessions refreshed.")
+	fmt.Fprintf(&builder, "supertokens_session_refreshes_total %d\n", recorder.sessionRefreshes)
+
+	writeHeader(&builder, "supertokens_token_theft_detections_total", "counter", "Token thefts detected.")
+	fmt.Fprintf(&builder, "supertokens_token_theft_detections_total %d\n", recorder.tokenTheftDetections)
+
+	writeHeader(&builder, "supertokens_errors_total", "counter", "Failed session operations, by type of error.")
+	errorKeys := [][2]string{}
+	for key := range recorder.operationErrors {
+		errorKeys = append(errorKeys, key)
+	}
+	sort.Slice(errorKeys, func(i, j int) bool {
+		return strings.Join(errorKeys[i][:], ";") < strings.Join(errorKeys[j][:], ";")
+	})
+	for _, key := range errorKeys {
+		fmt.Fprintf(&builder, "supertokens_errors_total{operation=\"%s\",type=\"%s\"} %d\n",
+			escapeLabelValue(key[0]), escapeLabelValue(key[1]), recorder.operationErrors[key])
+	}
+
+	writeHeader(&builder, "supertokens_core_requests_total", "counter", "Requests to the core, by host, path and status.")
+	requestKeys := [][3]string{}
+	for key := range recorder.coreRequests {
+		requestKeys = append(requestKeys, key)
+	}
+	sort.Slice(requestKeys, func(i, j int) bool {
+		return strings.Join(requestKeys[i][:], ";") < strings.Join(requestKeys[j][:], ";")
+	})
+	for _, key := range requestKeys {
+		fmt.Fprintf(&builder, "supertokens_core_requests_total{host=\"%s\",path=\"%s\",status=\"%s\"} %d\n",
+			escapeLabelValue(key[0]), escapeLabelValue(key[1]), escapeLabelValue(key[2]), recorder.coreRequests[key])
+	}
+
+	writeHeader(&builder, "supertokens_core_request_duration_seconds", "histogram", "Latency of requests to the core, by host.")
+	hosts := []string{}
+	for host := range recorder.coreRequestDurations {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		durationHistogram := recorder.coreRequestDurations[host]
+		label := escapeLabelValue(host)
+		for i, bucket := range durationBuckets {
+			fmt.Fprintf(&builder, "supertokens_core_request_duration_seconds_bucket{host=\"%s\",le=\"%s\"} %d\n",
+				label, strconv.FormatFloat(bucket, 'g', -1, 64), durationHistogram.counts[i])
+		}
+		fmt.Fprintf(&builder, "supertokens_core_request_duration_seconds_bucket{host=\"%s\",le=\"+Inf\"} %d\n",
+			label, durationHistogram.count)
+		fmt.Fprintf(&builder, "supertokens_core_request_duration_seconds_sum{host=\"%s\"} %s\n",
+			label, strconv.FormatFloat(durationHistogram.sum, 'g', -1, 64))
+		fmt.Fprintf(&builder, "supertokens_core_request_duration_seconds_count{host=\"%s\"} %d\n",
+			label, durationHistogram.count)
+	}
+
+	written, err := io.WriteString(w, builder.String())
+	return int64(written), err
+}
+
+func writeHeader(builder *strings.Builder, name string, metricType string, help string) {
+	fmt.Fprintf(builder, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func escapeLabelValue(value string) string {
+	value = strings.Replace(value, "\\", "\\\\", -1)
+	value = strings.Replace(value, "\"", "\\\"", -1)
+	return strings.Replace(value, "\n", "\\n", -1)
+}
+
+func getErrorType(err error) string {
+	if errors.IsUnauthorizedError(err) {
+		return "unauthorised"
+	} else if errors.IsTryRefreshTokenError(err) {
+		return "try_refresh_token"
+	} else if errors.IsTokenTheftDetectedError(err) {
+		return "token_theft_detected"
+	}
+	return "general"
+}
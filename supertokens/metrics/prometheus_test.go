@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package metrics
+
+import (
+	goErrors "errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/supertokens/supertokens-go/supertokens/core"
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+func TestPrometheusExposition(t *testing.T) {
+	recorder := NewPrometheusRecorder()
+	recorder.SessionVerified(core.VerifiedLocally)
+	recorder.SessionVerified(core.VerifiedLocally)
+	recorder.SessionVerified(core.VerifiedByCore)
+	recorder.SessionRefreshed()
+	recorder.TokenTheftDetected()
+	recorder.OperationFailed(core.GetSessionOperation, errors.TryRefreshTokenError{Msg: "expired"})
+	recorder.CoreRequestCompleted("http://localhost:8080", "/session/verify", 200, 20*time.Millisecond, nil)
+	recorder.CoreRequestCompleted("http://localhost:8080", "/session/verify", 0, time.Second, goErrors.New("timeout"))
+
+	response := httptest.NewRecorder()
+	recorder.ServeHTTP(response, httptest.NewRequest("GET", "/metrics", nil))
+	body := response.Body.String()
+
+	expected := []string{
+		`supertokens_session_verifications_total{method="core"} 1`,
+		`supertokens_session_verifications_total{method="local"} 2`,
+		`supertokens_session_refreshes_total 1`,
+		`supertokens_token_theft_detections_total 1`,
+		`supertokens_errors_total{operation="getSession",type="try_refresh_token"} 1`,
+		`supertokens_core_requests_total{host="http://localhost:8080",path="/session/verify",status="200"} 1`,
+		`supertokens_core_requests_total{host="http://localhost:8080",path="/session/verify",status="error"} 1`,
+		`supertokens_core_request_duration_seconds_bucket{host="http://localhost:8080",le="0.025"} 1`,
+		`supertokens_core_request_duration_seconds_bucket{host="http://localhost:8080",le="1"} 2`,
+		`supertokens_core_request_duration_seconds_bucket{host="http://localhost:8080",le="+Inf"} 2`,
+		`supertokens_core_request_duration_seconds_count{host="http://localhost:8080"} 2`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line+"\n") {
+			t.Error("missing line:", line)
+		}
+	}
+	if !strings.HasPrefix(response.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Error("incorrect content type")
+	}
+}
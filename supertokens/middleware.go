@@ -24,60 +24,122 @@ import (
 	"github.com/supertokens/supertokens-go/supertokens/errors"
 )
 
+// MiddlewareOptions add key value params for the routes protected by NewMiddleware
+type MiddlewareOptions struct {
+	// AntiCsrfCheck overrides doing the anti-csrf check for all requests except GET requests
+	AntiCsrfCheck *bool
+	// SessionOptional lets requests without a valid session through. GetSessionFromRequest returns nil for them
+	SessionOptional bool
+	// OnError replaces HandleErrorAndRespond for errors of these routes
+	OnError func(error, http.ResponseWriter)
+	// SkipClaimValidation lets sessions through whose SessionClaims are stale or invalid, for example on the
+	// route that verifies an email address
+	SkipClaimValidation bool
+}
+
 // Middleware for verifying and refreshing session. ExtraParams are: bool, func(error, http.ResponseWriter)
 func Middleware(theirHandler http.HandlerFunc, extraParams ...interface{}) http.HandlerFunc {
+	options := MiddlewareOptions{}
+	if len(extraParams) != 0 && extraParams[0] != nil {
+		doAntiCsrfCheck := extraParams[0].(bool)
+		options.AntiCsrfCheck = &doAntiCsrfCheck
+	}
+	if len(extraParams) == 2 {
+		options.OnError = extraParams[1].(func(err error, w http.ResponseWriter))
+	}
+	return sessionHandler(theirHandler, options)
+}
+
+// NewMiddleware returns a middleware for verifying and refreshing session that can be used with
+// routers such as chi and gorilla/mux. At most one MiddlewareOptions is used
+func NewMiddleware(options ...MiddlewareOptions) func(http.Handler) http.Handler {
+	actualOptions := MiddlewareOptions{}
+	if len(options) != 0 {
+		actualOptions = options[0]
+	}
+	return func(next http.Handler) http.Handler {
+		return sessionHandler(next, actualOptions)
+	}
+}
+
+func sessionHandler(theirHandler http.Handler, options MiddlewareOptions) http.HandlerFunc {
+	handleError := HandleErrorAndRespond
+	if options.OnError != nil {
+		handleError = options.OnError
+	}
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "OPTIONS" || r.Method == "TRACE" {
-			theirHandler.ServeHTTP(w, r)
+		session, sessionError := handleSession(wrapResponse(w), wrapRequest(r), options)
+		if sessionError != nil {
+			handleError(sessionError, w)
 			return
 		}
-		var path = r.URL.Path
-		handshakeInfo, handshakeInfoError := core.GetHandshakeInfoInstance()
-		if handshakeInfoError != nil {
-			if len(extraParams) != 2 {
-				HandleErrorAndRespond(handshakeInfoError, w)
-			} else {
-				extraParams[1].(func(err error, w http.ResponseWriter))(handshakeInfoError, w)
-			}
-			return
+		if session != nil {
+			ctx := context.WithValue(r.Context(), sessionContext, *session)
+			r = r.WithContext(ctx)
 		}
-		refreshTokenPath := handshakeInfo.RefreshTokenPath
-		if configMap != nil && configMap.RefreshAPIPath != "" {
-			refreshTokenPath = configMap.RefreshAPIPath
+		theirHandler.ServeHTTP(w, r)
+	})
+}
+
+// HandleSession function used by middlewares of frameworks that are built on net/http. It works like
+// HandleSessionWithWrapper
+func HandleSession(w http.ResponseWriter, r *http.Request, options ...MiddlewareOptions) (*Session, error) {
+	return HandleSessionWithWrapper(wrapResponse(w), wrapRequest(r), options...)
+}
+
+// HandleSessionWithWrapper function used by middlewares of frameworks that are not built on net/http. It refreshes
+// the session for the refresh API and verifies it otherwise. The session is nil if the request has to be let through
+// without one. OnError of options is not used
+func HandleSessionWithWrapper(response ResponseWrapper, request RequestWrapper,
+	options ...MiddlewareOptions) (*Session, error) {
+	actualOptions := MiddlewareOptions{}
+	if len(options) != 0 {
+		actualOptions = options[0]
+	}
+	return handleSession(response, request, actualOptions)
+}
+
+func handleSession(response ResponseWrapper, request RequestWrapper, options MiddlewareOptions) (*Session, error) {
+	method := request.GetMethod()
+	if method == "OPTIONS" || method == "TRACE" {
+		return nil, nil
+	}
+	var path = request.GetPath()
+	refreshTokenPath, refreshTokenPathError := GetRefreshAPIPath()
+	if refreshTokenPathError != nil {
+		return nil, refreshTokenPathError
+	}
+	if (refreshTokenPath == path ||
+		(refreshTokenPath+"/") == path ||
+		refreshTokenPath == (path+"/")) &&
+		method == "POST" {
+		session, sessionError := refreshSession(response, request)
+		if sessionError != nil {
+			return nil, sessionError
 		}
-		if (refreshTokenPath == path ||
-			(refreshTokenPath+"/") == path ||
-			refreshTokenPath == (path+"/")) &&
-			r.Method == "POST" {
-			session, sessionError := RefreshSession(w, r)
-			if sessionError != nil {
-				if len(extraParams) != 2 {
-					HandleErrorAndRespond(sessionError, w)
-				} else {
-					extraParams[1].(func(err error, w http.ResponseWriter))(sessionError, w)
-				}
-				return
-			}
-			ctx := context.WithValue(r.Context(), sessionContext, session)
-			theirHandler.ServeHTTP(w, r.WithContext(ctx))
-		} else {
-			var actualDoAntiCsrfCheck = r.Method != "GET"
-			if len(extraParams) != 0 && extraParams[0] != nil {
-				actualDoAntiCsrfCheck = extraParams[0].(bool)
-			}
-			session, sessionError := GetSession(w, r, actualDoAntiCsrfCheck)
-			if sessionError != nil {
-				if len(extraParams) != 2 {
-					HandleErrorAndRespond(sessionError, w)
-				} else {
-					extraParams[1].(func(err error, w http.ResponseWriter))(sessionError, w)
-				}
-				return
-			}
-			ctx := context.WithValue(r.Context(), sessionContext, session)
-			theirHandler.ServeHTTP(w, r.WithContext(ctx))
+		return &session, nil
+	}
+	// anonymous requests are let through without verifying anything or clearing cookies
+	if options.SessionOptional && getIDRefreshTokenFromCookie(request) == nil &&
+		getAccessTokenFromCookie(request) == nil {
+		saveFrontendInfoFromRequest(request)
+		return nil, nil
+	}
+	var actualDoAntiCsrfCheck = method != "GET"
+	if options.AntiCsrfCheck != nil {
+		actualDoAntiCsrfCheck = *options.AntiCsrfCheck
+	}
+	session, sessionError := getSession(response, request, actualDoAntiCsrfCheck,
+		!options.SkipClaimValidation)
+	if sessionError != nil {
+		// an expired access token still has to be refreshed by the frontend
+		if options.SessionOptional && errors.IsUnauthorizedError(sessionError) {
+			return nil, nil
 		}
-	})
+		return nil, sessionError
+	}
+	setRefreshHintInHeaders(response, session.GetAccessTokenExpiry())
+	return &session, nil
 }
 
 // HandleErrorAndRespond if error handlers are provided, then uses those, else does default error handling depending on the type of error
@@ -90,6 +152,12 @@ func HandleErrorAndRespond(err error, w http.ResponseWriter) {
 	} else if errors.IsTokenTheftDetectedError(err) {
 		actualError := err.(errors.TokenTheftDetectedError)
 		errorHandlers.OnTokenTheftDetectedErrorHandler(actualError.SessionHandle, actualError.UserID, w)
+	} else if errors.IsForbiddenError(err) {
+		errorHandlers.OnForbiddenErrorHandler(err, w)
+	} else if errors.IsInvalidClaimError(err) {
+		errorHandlers.OnInvalidClaimErrorHandler(err, w)
+	} else if errors.IsReauthenticationRequiredError(err) {
+		errorHandlers.OnReauthenticationRequiredHandler(err, w)
 	} else {
 		errorHandlers.OnGeneralErrorHandler(err, w)
 	}
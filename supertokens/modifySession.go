@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// sessionLock serialises modifications of one session within this process
+type sessionLock struct {
+	sync.Mutex
+	users int
+}
+
+// sessionLocks only holds the locks of sessions that are being modified, so that modifications of
+// different sessions never wait for each other
+var sessionLocks = map[string]*sessionLock{}
+var sessionLocksLock sync.Mutex
+
+// lockSession locks sessionHandle and returns the function that unlocks it
+func lockSession(sessionHandle string) func() {
+	sessionLocksLock.Lock()
+	lock, ok := sessionLocks[sessionHandle]
+	if !ok {
+		lock = &sessionLock{}
+		sessionLocks[sessionHandle] = lock
+	}
+	lock.users++
+	sessionLocksLock.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		sessionLocksLock.Lock()
+		lock.users--
+		if lock.users == 0 {
+			delete(sessionLocks, sessionHandle)
+		}
+		sessionLocksLock.Unlock()
+	}
+}
+
+// ModifySessionData function used to change the session data of this session. modify is called with a
+// copy of the current data, and if it returns an error nothing is saved. Modifications of the same session
+// made in this process are applied one after the other, but the core has no conditional update, so a
+// change made by another process between reading and saving the data is overwritten
+func (session *Session) ModifySessionData(modify func(map[string]interface{}) error) error {
+	return modifySession(session.sessionHandle, session.getSessionDataFromCore, modify, session.UpdateSessionData)
+}
+
+// MergeSessionData function used to apply a JSON merge patch (RFC 7386) to the session data of this
+// session. Keys set to nil in patch are removed. It is serialised like ModifySessionData
+func (session *Session) MergeSessionData(patch map[string]interface{}) error {
+	return session.ModifySessionData(func(data map[string]interface{}) error {
+		applyMergePatch(data, patch)
+		return nil
+	})
+}
+
+// MergeJWTPayload function used to apply a JSON merge patch (RFC 7386) to the jwt payload of this
+// session. Keys set to nil in patch are removed. It is serialised like ModifySessionData
+func (session *Session) MergeJWTPayload(patch map[string]interface{}) error {
+	return modifySession(session.sessionHandle,
+		func() (map[string]interface{}, error) {
+			return GetJWTPayload(session.sessionHandle)
+		},
+		func(payload map[string]interface{}) error {
+			applyMergePatch(payload, patch)
+			return nil
+		}, session.UpdateJWTPayload)
+}
+
+// modifySession reads, modifies and writes the data of a session while holding its lock
+func modifySession(sessionHandle string, read func() (map[string]interface{}, error),
+	modify func(map[string]interface{}) error, write func(map[string]interface{}) error) error {
+	unlock := lockSession(sessionHandle)
+	defer unlock()
+	original, err := read()
+	if err != nil {
+		return err
+	}
+	modified, err := copyMap(original)
+	if err != nil {
+		return err
+	}
+	if err = modify(modified); err != nil {
+		return err
+	}
+	return write(modified)
+}
+
+// applyMergePatch changes target as described in RFC 7386
+func applyMergePatch(target map[string]interface{}, patch map[string]interface{}) {
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+		patchMap, isPatchMap := value.(map[string]interface{})
+		if !isPatchMap {
+			target[key] = value
+			continue
+		}
+		targetMap, isTargetMap := target[key].(map[string]interface{})
+		if !isTargetMap {
+			targetMap = map[string]interface{}{}
+		}
+		applyMergePatch(targetMap, patchMap)
+		target[key] = targetMap
+	}
+}
+
+func copyMap(original map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(original)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]interface{}{}
+	if err = json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	if result == nil {
+		result = map[string]interface{}{}
+	}
+	return result, nil
+}
@@ -17,7 +17,8 @@
 package supertokens
 
 import (
-	"net/http"
+	"sync"
+	"time"
 
 	"github.com/supertokens/supertokens-go/supertokens/core"
 	"github.com/supertokens/supertokens-go/supertokens/errors"
@@ -29,12 +30,57 @@ type Session struct {
 	userID        string
 	userDataInJWT map[string]interface{}
 	accessToken   string
-	response      http.ResponseWriter
+	response      ResponseWrapper
+	request       RequestWrapper
+	// cache is shared by all copies of a Session so that it lasts for the whole request
+	cache *sessionCache
+}
+
+// sessionCache memoizes the session data of a session for the lifetime of a request
+type sessionCache struct {
+	lock        sync.Mutex
+	sessionData map[string]interface{}
+}
+
+func newSessionCache() *sessionCache {
+	if configMap != nil && configMap.DisableSessionDataCaching {
+		return nil
+	}
+	return &sessionCache{}
+}
+
+func (cache *sessionCache) getSessionData() map[string]interface{} {
+	if cache == nil {
+		return nil
+	}
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	if cache.sessionData == nil {
+		return nil
+	}
+	// a copy is returned so that changes made by the caller are not cached
+	data, err := copyMap(cache.sessionData)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func (cache *sessionCache) setSessionData(data map[string]interface{}) {
+	if cache == nil {
+		return
+	}
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	cache.sessionData = nil
+	if data != nil {
+		cache.sessionData, _ = copyMap(data)
+	}
 }
 
 // RevokeSession function used to revoke a session for this session
 func (session *Session) RevokeSession() error {
-	success, err := RevokeSession(session.sessionHandle)
+	success, err := revokeSession(session.sessionHandle, session.userID, session.request)
 	if err != nil {
 		return err
 	}
@@ -55,8 +101,37 @@ func (session *Session) RevokeSession() error {
 	return nil
 }
 
-// GetSessionData function used to get session data for this session
+// RevokeAllOtherSessions function used to revoke all sessions of this user except this one. Returns the
+// revoked session handles, and a PartialFailureError if some of the sessions could not be revoked
+func (session *Session) RevokeAllOtherSessions() ([]string, error) {
+	sessionHandles, err := GetAllSessionHandlesForUser(session.userID)
+	if err != nil {
+		return nil, err
+	}
+	otherSessionHandles := []string{}
+	for _, sessionHandle := range sessionHandles {
+		if sessionHandle != session.sessionHandle {
+			otherSessionHandles = append(otherSessionHandles, sessionHandle)
+		}
+	}
+	return revokeMultipleSessionsInChunks(otherSessionHandles)
+}
+
+// GetSessionData function used to get session data for this session. The result is reused for the rest of
+// the request unless DisableSessionDataCaching is set
 func (session *Session) GetSessionData() (map[string]interface{}, error) {
+	if data := session.cache.getSessionData(); data != nil {
+		return data, nil
+	}
+	data, err := session.getSessionDataFromCore()
+	if err != nil {
+		return nil, err
+	}
+	session.cache.setSessionData(data)
+	return data, nil
+}
+
+func (session *Session) getSessionDataFromCore() (map[string]interface{}, error) {
 	data, err := GetSessionData(session.sessionHandle)
 	if err != nil {
 		if errors.IsUnauthorizedError(err) {
@@ -78,8 +153,18 @@ func (session *Session) GetSessionData() (map[string]interface{}, error) {
 	return data, nil
 }
 
+// GetDeviceMetadata function used to get the device this session was created on. Returns nil if it was not stored
+func (session *Session) GetDeviceMetadata() (*DeviceMetadata, error) {
+	data, err := session.GetSessionData()
+	if err != nil {
+		return nil, err
+	}
+	return GetDeviceMetadataFromSessionData(data), nil
+}
+
 // UpdateSessionData function used to update session data for this session
 func (session *Session) UpdateSessionData(newSessionData map[string]interface{}) error {
+	session.cache.setSessionData(nil)
 	err := UpdateSessionData(session.sessionHandle, newSessionData)
 	if err != nil {
 		if errors.IsUnauthorizedError(err) {
@@ -121,8 +206,46 @@ func (session *Session) GetAccessToken() string {
 	return session.accessToken
 }
 
+// GetAccessTokenPayload function gets all the claims of the verified access token for this session
+func (session *Session) GetAccessTokenPayload() map[string]interface{} {
+	payload, err := core.DecodeAccessTokenPayloadUnverified(session.accessToken)
+	if err != nil {
+		return map[string]interface{}{}
+	}
+	return payload
+}
+
+// GetAccessTokenExpiry function gets the time in milliseconds at which the access token of this session expires
+func (session *Session) GetAccessTokenExpiry() uint64 {
+	return getUint64Claim(session.GetAccessTokenPayload(), "expiryTime")
+}
+
+// GetTimeCreated function gets the time in milliseconds at which the access token of this session was created
+func (session *Session) GetTimeCreated() uint64 {
+	return getUint64Claim(session.GetAccessTokenPayload(), "timeCreated")
+}
+
+// GetTimeUntilExpiry function gets the time left before the access token of this session expires
+func (session *Session) GetTimeUntilExpiry() time.Duration {
+	expiry := time.Unix(0, int64(session.GetAccessTokenExpiry())*int64(time.Millisecond))
+	timeLeft := time.Until(expiry)
+	if timeLeft < 0 {
+		return 0
+	}
+	return timeLeft
+}
+
+func getUint64Claim(payload map[string]interface{}, key string) uint64 {
+	value, ok := payload[key].(float64)
+	if !ok {
+		return 0
+	}
+	return uint64(value)
+}
+
 // UpdateJWTPayload function used to update jwt payload for this session
 func (session *Session) UpdateJWTPayload(newJWTPayload map[string]interface{}) error {
+	newJWTPayload = keepImpersonation(session.userDataInJWT, newJWTPayload)
 	sessionInfo, err := core.RegenerateSession(session.accessToken, newJWTPayload)
 	if err != nil {
 		if errors.IsUnauthorizedError(err) {
@@ -154,5 +277,12 @@ func (session *Session) UpdateJWTPayload(newJWTPayload map[string]interface{}) e
 			(*sessionInfo.AccessToken).SameSite,
 		)
 	}
+	core.GetSessionHooksInstance().OnJWTPayloadUpdatedHook(core.SessionEvent{
+		SessionHandle:  session.sessionHandle,
+		UserID:         session.userID,
+		Request:        getHTTPRequest(session.request),
+		RequestWrapper: session.request,
+		JWTPayload:     session.userDataInJWT,
+	})
 	return nil
 }
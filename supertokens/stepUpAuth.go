@@ -0,0 +1,153 @@
+/*
+ * Copyright (c) 2020, VRAI Labs and/or its affiliates. All rights reserved.
+ *
+ * This software is licensed under the Apache License, Version 2.0 (the
+ * "License") as published by the Apache Software Foundation.
+ *
+ * You may not use this file except in compliance with the License. You may
+ * obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ */
+
+package supertokens
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/supertokens/supertokens-go/supertokens/errors"
+)
+
+// AuthTimeKey is the key in the JWT payload of the time in milliseconds at which the user last authenticated
+const AuthTimeKey = "authTime"
+
+// AssuranceLevelKey is the key in the JWT payload of how strongly the user last authenticated, for example 2
+// after a second factor
+const AssuranceLevelKey = "assuranceLevel"
+
+// DefaultAssuranceLevel is recorded for new sessions whose JWT payload has no assurance level, and is the
+// assurance level of sessions that did not record one
+const DefaultAssuranceLevel = 1
+
+// WithAssuranceLevel returns a copy of payload to pass to CreateNewSession for a user that authenticated with
+// assuranceLevel. The session records its authentication even if RecordAuthentication is not set
+func WithAssuranceLevel(payload map[string]interface{}, assuranceLevel int) map[string]interface{} {
+	result := map[string]interface{}{}
+	for key, value := range payload {
+		result[key] = value
+	}
+	result[AssuranceLevelKey] = assuranceLevel
+	return result
+}
+
+// addAuthenticationToJWTPayload records the authentication of a new session if RecordAuthentication is set or
+// the app gave an assurance level, keeping values set by the app
+func addAuthenticationToJWTPayload(jwtPayload map[string]interface{}) map[string]interface{} {
+	// the user of an impersonation session did not authenticate
+	if _, impersonated := jwtPayload[ActorIDKey]; impersonated {
+		return jwtPayload
+	}
+	_, hasAssuranceLevel := jwtPayload[AssuranceLevelKey]
+	if !hasAssuranceLevel && (configMap == nil || !configMap.RecordAuthentication) {
+		return jwtPayload
+	}
+	result := map[string]interface{}{
+		AuthTimeKey:       getCurrentTimeInMS(),
+		AssuranceLevelKey: DefaultAssuranceLevel,
+	}
+	for key, value := range jwtPayload {
+		result[key] = value
+	}
+	return result
+}
+
+func getCurrentTimeInMS() uint64 {
+	return uint64(time.Now().UnixNano() / int64(time.Millisecond))
+}
+
+// GetAuthTime function gets the time at which the user of this session last authenticated. It falls back to the
+// zero time, which CheckRecentAuth never accepts, if the JWT payload has none because RecordAuthentication is not
+// set or UpdateJWTPayload replaced it
+func (session *Session) GetAuthTime() time.Time {
+	authTime := getUint64Claim(session.GetJWTPayload(), AuthTimeKey)
+	if authTime == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(authTime)*int64(time.Millisecond))
+}
+
+// GetAssuranceLevel function gets how strongly the user of this session last authenticated. It falls back to
+// DefaultAssuranceLevel if the JWT payload has none
+func (session *Session) GetAssuranceLevel() int {
+	assuranceLevel := getUint64Claim(session.GetJWTPayload(), AssuranceLevelKey)
+	if assuranceLevel == 0 {
+		return DefaultAssuranceLevel
+	}
+	return int(assuranceLevel)
+}
+
+// MarkReauthenticated function used to record that the user of this session authenticated again. The assurance
+// level is only changed if one is given
+func (session *Session) MarkReauthenticated(assuranceLevel ...int) error {
+	patch := map[string]interface{}{
+		AuthTimeKey: getCurrentTimeInMS(),
+	}
+	if len(assuranceLevel) != 0 {
+		patch[AssuranceLevelKey] = assuranceLevel[0]
+	}
+	return session.MergeJWTPayload(patch)
+}
+
+// CheckRecentAuth function used to get a ReauthenticationRequiredError if the user of session authenticated more
+// than maxAge ago or with an assurance level below minAssuranceLevel. Impersonation sessions get a ForbiddenError,
+// since the impersonating user cannot authenticate as the user
+func CheckRecentAuth(session Session, maxAge time.Duration, minAssuranceLevel ...int) error {
+	if session.IsImpersonated() {
+		return errors.ForbiddenError{
+			Msg: "recent authentication cannot be proven while impersonating a user",
+		}
+	}
+	reauthenticationError := errors.ReauthenticationRequiredError{
+		MaxAge: uint64(maxAge / time.Millisecond),
+	}
+	if len(minAssuranceLevel) != 0 {
+		reauthenticationError.MinAssuranceLevel = minAssuranceLevel[0]
+		if session.GetAssuranceLevel() < minAssuranceLevel[0] {
+			reauthenticationError.Msg = "assurance level " + strconv.Itoa(minAssuranceLevel[0]) + " is required"
+			return reauthenticationError
+		}
+	}
+	authTime := session.GetAuthTime()
+	if authTime.IsZero() || time.Since(authTime) > maxAge {
+		reauthenticationError.Msg = "last authentication is older than " + maxAge.String()
+		return reauthenticationError
+	}
+	return nil
+}
+
+// RequireRecentAuth returns a middleware that responds with a ReauthenticationRequiredError unless the user
+// authenticated within maxAge, with at least minAssuranceLevel if given. It has to run after the session middleware
+func RequireRecentAuth(maxAge time.Duration, minAssuranceLevel ...int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			session := GetSessionFromRequest(r)
+			if session == nil {
+				HandleErrorAndRespond(errors.UnauthorizedError{
+					Msg: "no session found for this request",
+				}, w)
+				return
+			}
+			if err := CheckRecentAuth(*session, maxAge, minAssuranceLevel...); err != nil {
+				HandleErrorAndRespond(err, w)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -17,7 +17,12 @@
 package supertokens
 
 import (
+	"context"
+	"log"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/supertokens/supertokens-go/supertokens/core"
 	"github.com/supertokens/supertokens-go/supertokens/errors"
@@ -36,17 +41,109 @@ type ConfigMap struct {
 	CookieSecure    *bool
 	CookieSameSite  string
 	APIKey          string
+	// VerifyCacheSize is the max number of successful verify results kept in memory. 0 disables the cache
+	VerifyCacheSize int
+	// VerifyCacheMaxAge is how long a verify result may be reused, if the access token has not expired before that
+	VerifyCacheMaxAge time.Duration
+	// EnableDegradedMode accepts locally verifiable access tokens while the core is unreachable
+	EnableDegradedMode bool
+	// DegradedModeMaxStaleness is how long after the last successful core contact degraded mode may be used
+	DegradedModeMaxStaleness time.Duration
+	// HandshakeStore persists handshake info so that sessions can be verified right after a cold start
+	HandshakeStore core.HandshakeStore
+	// RefreshHintWindow makes the middleware recommend a refresh in the response headers when the
+	// access token expires within this duration. 0 disables the headers
+	RefreshHintWindow time.Duration
+	// Logger receives debug events about how sessions are verified. A *slog.Logger can be used directly
+	Logger core.Logger
+	// MetricsRecorder is called on session operations and core requests
+	MetricsRecorder core.MetricsRecorder
+	// Tracer creates spans around session operations and requests to the core
+	Tracer core.Tracer
+	// TokenTheftPolicy replaces revoking only the affected session when token theft is detected
+	TokenTheftPolicy *core.TokenTheftPolicy
+	// DeviceMetadataExtractor stores device metadata in the session data of sessions created while handling a
+	// request. DefaultDeviceMetadataExtractor can be used. nil disables it
+	DeviceMetadataExtractor func(RequestWrapper) DeviceMetadata
+	// DisableSessionDataCaching makes every Session.GetSessionData call query the core. Otherwise
+	// session data is fetched once per request
+	DisableSessionDataCaching bool
+	// ConnectionReverifyInterval makes connections tracked with TrackConnection have their access token verified
+	// this often. 0 only closes them on revocation in this process and on access token expiry
+	ConnectionReverifyInterval time.Duration
+	// WebSocketAllowedOrigins are the origins, such as "https://example.com", that GetSessionForWebSocket accepts.
+	// If empty, only the origin of the host the request was sent to is accepted
+	WebSocketAllowedOrigins []string
+	// RolesClaimPath is the path of the roles of a user in the JWT payload, with nested keys separated by dots.
+	// Defaults to DefaultRolesClaimPath
+	RolesClaimPath string
+	// SessionClaims are refetched once stale and validated whenever a session is verified
+	SessionClaims []SessionClaim
+	// RecordAuthentication adds AuthTimeKey and AssuranceLevelKey to the JWT payload of new sessions, for
+	// RequireRecentAuth. Sessions created with a payload from WithAssuranceLevel record them either way
+	RecordAuthentication bool
+	// ImpersonationMaxLifetime is how long sessions created with CreateImpersonationSession can be used.
+	// Defaults to DefaultImpersonationMaxLifetime
+	ImpersonationMaxLifetime time.Duration
 }
 
 // Config used to set locations of SuperTokens instances
 func Config(config ConfigMap) {
 	configCookieAndHeaders(config)
+	core.SetLogger(config.Logger)
+	core.SetMetricsRecorder(config.MetricsRecorder)
+	core.SetTracer(config.Tracer)
 	core.Config(config.Hosts, config.APIKey)
+	core.ConfigVerifyCache(config.VerifyCacheSize, uint64(config.VerifyCacheMaxAge/time.Millisecond))
+	core.ConfigDegradedMode(config.EnableDegradedMode, uint64(config.DegradedModeMaxStaleness/time.Millisecond))
+	core.ConfigHandshakeStore(config.HandshakeStore)
+	core.ConfigTokenTheftPolicy(config.TokenTheftPolicy)
+	core.ConfigTokenTheftRevoker(func(sessionHandle string, userID string) {
+		_, _ = revokeSession(sessionHandle, userID, nil)
+	})
+}
+
+// NewStandardLogger returns a Logger that writes debug events to a *log.Logger
+func NewStandardLogger(logger *log.Logger) core.Logger {
+	return core.NewStandardLogger(logger)
+}
+
+// NewFileHandshakeStore returns a HandshakeStore that keeps the handshake info in a file at path
+func NewFileHandshakeStore(path string) core.HandshakeStore {
+	return core.NewFileHandshakeStore(path)
+}
+
+// NewInMemoryHandshakeStore returns a HandshakeStore that keeps the handshake info for the lifetime of the process
+func NewInMemoryHandshakeStore() core.HandshakeStore {
+	return core.NewInMemoryHandshakeStore()
 }
 
 // CreateNewSession function used to create a new SuperTokens session
 func CreateNewSession(response http.ResponseWriter,
 	userID string, payload ...map[string]interface{}) (Session, error) {
+	return CreateNewSessionWithContext(context.Background(), response, userID, payload...)
+}
+
+// CreateNewSessionWithContext function used to create a new SuperTokens session as part of the trace in ctx
+func CreateNewSessionWithContext(ctx context.Context, response http.ResponseWriter,
+	userID string, payload ...map[string]interface{}) (Session, error) {
+	return createNewSession(ctx, wrapResponse(response), nil, userID, payload...)
+}
+
+// CreateNewSessionWithRequest function used to create a new SuperTokens session while handling request
+func CreateNewSessionWithRequest(response http.ResponseWriter, request *http.Request,
+	userID string, payload ...map[string]interface{}) (Session, error) {
+	return createNewSession(request.Context(), wrapResponse(response), wrapRequest(request), userID, payload...)
+}
+
+// CreateNewSessionWithWrapper function used to create a new SuperTokens session for frameworks that are not built on net/http
+func CreateNewSessionWithWrapper(response ResponseWrapper, request RequestWrapper,
+	userID string, payload ...map[string]interface{}) (Session, error) {
+	return createNewSession(request.Context(), response, request, userID, payload...)
+}
+
+func createNewSession(ctx context.Context, response ResponseWrapper, request RequestWrapper,
+	userID string, payload ...map[string]interface{}) (Session, error) {
 
 	var jwtPayload = map[string]interface{}{}
 	var sessionData = map[string]interface{}{}
@@ -60,8 +157,26 @@ func CreateNewSession(response http.ResponseWriter,
 			sessionData = payload[1]
 		}
 	}
+	for _, key := range []string{ActorIDKey, ImpersonationExpiryKey} {
+		if _, ok := jwtPayload[key]; ok {
+			return Session{}, errors.GeneralError{
+				Msg: key + " is reserved for sessions created with CreateImpersonationSession",
+			}
+		}
+	}
+	return startSession(ctx, response, request, userID, jwtPayload, sessionData)
+}
 
-	session, err := core.CreateNewSession(userID, jwtPayload, sessionData)
+// startSession creates a session in the core and attaches its tokens to the response
+func startSession(ctx context.Context, response ResponseWrapper, request RequestWrapper, userID string,
+	jwtPayload map[string]interface{}, sessionData map[string]interface{}) (Session, error) {
+	if sessionData == nil {
+		sessionData = map[string]interface{}{}
+	}
+	sessionData = addDeviceMetadataToSessionData(sessionData, request)
+	jwtPayload = addAuthenticationToJWTPayload(jwtPayload)
+
+	session, err := core.CreateNewSessionWithContext(ctx, userID, jwtPayload, sessionData)
 
 	if err != nil {
 		return Session{}, err
@@ -106,12 +221,23 @@ func CreateNewSession(response http.ResponseWriter,
 		setAntiCsrfTokenInHeaders(response, *session.AntiCsrfToken)
 	}
 
+	core.GetSessionHooksInstance().OnSessionCreatedHook(core.SessionEvent{
+		SessionHandle:  session.Handle,
+		UserID:         session.UserID,
+		Request:        getHTTPRequest(request),
+		RequestWrapper: request,
+		JWTPayload:     session.UserDataInJWT,
+		SessionData:    sessionData,
+	})
+
 	return Session{
 		accessToken:   accessToken.Token,
 		sessionHandle: session.Handle,
 		userID:        session.UserID,
 		userDataInJWT: session.UserDataInJWT,
 		response:      response,
+		request:       request,
+		cache:         newSessionCache(),
 	}, nil
 
 }
@@ -119,10 +245,22 @@ func CreateNewSession(response http.ResponseWriter,
 // GetSession function used to verify a session
 func GetSession(response http.ResponseWriter, request *http.Request,
 	doAntiCsrfCheck bool) (Session, error) {
+	return getSession(wrapResponse(response), wrapRequest(request), doAntiCsrfCheck, true)
+}
+
+// GetSessionWithWrapper function used to verify a session for frameworks that are not built on net/http
+func GetSessionWithWrapper(response ResponseWrapper, request RequestWrapper,
+	doAntiCsrfCheck bool) (Session, error) {
+	return getSession(response, request, doAntiCsrfCheck, true)
+}
+
+func getSession(response ResponseWrapper, request RequestWrapper,
+	doAntiCsrfCheck bool, validateClaims bool) (Session, error) {
 	saveFrontendInfoFromRequest(request)
 
 	idRefreshToken := getIDRefreshTokenFromCookie(request)
 	if idRefreshToken == nil {
+		core.LogDebug("idRefreshToken missing in cookies", "path", request.GetPath())
 		handShakeInfo, handshakeInfoError := core.GetHandshakeInfoInstance()
 		if handshakeInfoError != nil {
 			return Session{}, handshakeInfoError
@@ -135,22 +273,27 @@ func GetSession(response http.ResponseWriter, request *http.Request,
 			handShakeInfo.IDRefreshTokenPath,
 			handShakeInfo.CookieSameSite,
 		)
-		return Session{}, errors.UnauthorizedError{
+		unauthorizedError := errors.UnauthorizedError{
 			Msg: "idRefreshToken missing",
 		}
+		core.GetMetricsRecorder().OperationFailed(core.GetSessionOperation, unauthorizedError)
+		return Session{}, unauthorizedError
 	}
 
 	accessToken := getAccessTokenFromCookie(request)
 	if accessToken == nil {
 		// maybe the access token has expired.
-		return Session{}, errors.TryRefreshTokenError{
+		core.LogDebug("access token missing in cookies", "path", request.GetPath())
+		tryRefreshTokenError := errors.TryRefreshTokenError{
 			Msg: "access token missing in cookies",
 		}
+		core.GetMetricsRecorder().OperationFailed(core.GetSessionOperation, tryRefreshTokenError)
+		return Session{}, tryRefreshTokenError
 	}
 
 	antiCsrfToken := getAntiCsrfTokenFromHeaders(request)
 
-	session, getSessionError := core.GetSession(*accessToken, antiCsrfToken, doAntiCsrfCheck)
+	session, getSessionError := core.GetSessionWithContext(request.Context(), *accessToken, antiCsrfToken, doAntiCsrfCheck)
 
 	if getSessionError != nil {
 		if errors.IsUnauthorizedError(getSessionError) {
@@ -183,20 +326,72 @@ func GetSession(response http.ResponseWriter, request *http.Request,
 		accessToken = &session.AccessToken.Token
 	}
 
-	return Session{
+	result := Session{
 		accessToken:   *accessToken,
 		response:      response,
 		sessionHandle: session.Handle,
 		userDataInJWT: session.UserDataInJWT,
 		userID:        session.UserID,
-	}, nil
+		request:       request,
+		cache:         newSessionCache(),
+	}
+	if err := revokeExpiredImpersonation(response, request, result.sessionHandle, result.userID,
+		result.userDataInJWT); err != nil {
+		return Session{}, err
+	}
+	if validateClaims {
+		if err := result.ValidateClaims(request.Context(), getSessionClaims()...); err != nil {
+			return Session{}, err
+		}
+	}
+	return result, nil
+}
+
+// GetSessionFromAccessToken function used to verify an access token that was not read from cookies, for example
+// one forwarded by another service. No tokens are set or cleared in a response. GetAccessToken of the returned
+// session differs from accessToken if the core issued a new one or stale SessionClaims were refetched
+func GetSessionFromAccessToken(ctx context.Context, accessToken string, antiCsrfToken *string,
+	doAntiCsrfCheck bool) (Session, error) {
+	session, err := core.GetSessionWithContext(ctx, accessToken, antiCsrfToken, doAntiCsrfCheck)
+	if err != nil {
+		return Session{}, err
+	}
+	if session.AccessToken != nil {
+		accessToken = session.AccessToken.Token
+	}
+	result := Session{
+		accessToken:   accessToken,
+		response:      noopResponseWrapper{},
+		sessionHandle: session.Handle,
+		userDataInJWT: session.UserDataInJWT,
+		userID:        session.UserID,
+		cache:         newSessionCache(),
+	}
+	if err := revokeExpiredImpersonation(result.response, nil, result.sessionHandle, result.userID,
+		result.userDataInJWT); err != nil {
+		return Session{}, err
+	}
+	if err := result.ValidateClaims(ctx, getSessionClaims()...); err != nil {
+		return Session{}, err
+	}
+	return result, nil
 }
 
 // RefreshSession function used to refresh a session
 func RefreshSession(response http.ResponseWriter, request *http.Request) (Session, error) {
+	return refreshSession(wrapResponse(response), wrapRequest(request))
+}
+
+// RefreshSessionWithWrapper function used to refresh a session for frameworks that are not built on net/http
+func RefreshSessionWithWrapper(response ResponseWrapper, request RequestWrapper) (Session, error) {
+	return refreshSession(response, request)
+}
+
+func refreshSession(response ResponseWrapper, request RequestWrapper) (Session, error) {
 	saveFrontendInfoFromRequest(request)
 	inputRefreshToken := getRefreshTokenFromCookie(request)
 	if inputRefreshToken == nil {
+		core.LogDebug("refresh token missing in cookies", "path", request.GetPath())
 		handShakeInfo, handshakeInfoError := core.GetHandshakeInfoInstance()
 		if handshakeInfoError != nil {
 			return Session{}, handshakeInfoError
@@ -209,13 +404,15 @@ func RefreshSession(response http.ResponseWriter, request *http.Request) (Sessio
 			handShakeInfo.RefreshTokenPath,
 			handShakeInfo.IDRefreshTokenPath,
 			handShakeInfo.CookieSameSite)
-		return Session{}, errors.UnauthorizedError{
+		unauthorizedError := errors.UnauthorizedError{
 			Msg: "Missing auth tokens in cookies. Have you set the correct refresh API path in your frontend and SuperTokens config?",
 		}
+		core.GetMetricsRecorder().OperationFailed(core.RefreshSessionOperation, unauthorizedError)
+		return Session{}, unauthorizedError
 	}
 
 	antiCsrfToken := getAntiCsrfTokenFromHeaders(request)
-	session, refreshError := core.RefreshSession(*inputRefreshToken, antiCsrfToken)
+	session, refreshError := core.RefreshSessionWithContext(request.Context(), *inputRefreshToken, antiCsrfToken)
 
 	if refreshError != nil {
 
@@ -233,9 +430,17 @@ func RefreshSession(response http.ResponseWriter, request *http.Request) (Sessio
 				handShakeInfo.IDRefreshTokenPath,
 				handShakeInfo.CookieSameSite)
 		}
+		if errors.IsTokenTheftDetectedError(refreshError) {
+			applyTokenTheftPolicy(refreshError.(errors.TokenTheftDetectedError), request)
+		}
 		return Session{}, refreshError
 	}
 
+	if err := revokeExpiredImpersonation(response, request, session.Handle, session.UserID,
+		session.UserDataInJWT); err != nil {
+		return Session{}, err
+	}
+
 	//attach cookies
 	accessToken := session.AccessToken
 	refreshToken := session.RefreshToken
@@ -275,18 +480,77 @@ func RefreshSession(response http.ResponseWriter, request *http.Request) (Sessio
 		setAntiCsrfTokenInHeaders(response, *session.AntiCsrfToken)
 	}
 
+	core.GetSessionHooksInstance().OnSessionRefreshedHook(core.SessionEvent{
+		SessionHandle:  session.Handle,
+		UserID:         session.UserID,
+		Request:        getHTTPRequest(request),
+		RequestWrapper: request,
+		JWTPayload:     session.UserDataInJWT,
+	})
+
 	return Session{
 		accessToken:   accessToken.Token,
 		sessionHandle: session.Handle,
 		userID:        session.UserID,
 		userDataInJWT: session.UserDataInJWT,
 		response:      response,
+		request:       request,
+		cache:         newSessionCache(),
 	}, nil
 }
 
+// applyTokenTheftPolicy rate limits the user right away and revokes sessions in the background
+func applyTokenTheftPolicy(theftError errors.TokenTheftDetectedError, request RequestWrapper) {
+	policyInstance := core.GetTokenTheftPolicyInstance()
+	policy := policyInstance.GetPolicy()
+	if policy == nil {
+		return
+	}
+	policyInstance.RateLimitUser(theftError.UserID)
+	event := core.TokenTheftEvent{
+		SessionHandle: theftError.SessionHandle,
+		UserID:        theftError.UserID,
+		RemoteAddr:    request.GetRemoteAddr(),
+		ForwardedFor:  request.GetHeader("X-Forwarded-For"),
+		UserAgent:     request.GetHeader("User-Agent"),
+		DetectedTime:  uint64(time.Now().UnixNano() / int64(time.Millisecond)),
+	}
+	go func() {
+		var revokeError error
+		if policy.RevokeAllSessionsForUser {
+			_, revokeError = RevokeAllSessionsForUser(event.UserID)
+		} else {
+			_, revokeError = RevokeSession(event.SessionHandle)
+		}
+		if revokeError != nil {
+			core.LogDebug("revoking sessions after token theft failed", "sessionHandle", event.SessionHandle,
+				"error", revokeError.Error())
+		}
+		if policy.Notify != nil {
+			policy.Notify(event, revokeError)
+		}
+	}()
+}
+
+// RevokeSessionAfterTokenTheft function used by token theft handlers to revoke the affected session, unless
+// TokenTheftPolicy revokes the sessions in the background
+func RevokeSessionAfterTokenTheft(sessionHandle string, userID string) {
+	core.RevokeSessionAfterTokenTheft(sessionHandle, userID)
+}
+
 // RevokeAllSessionsForUser function used to revoke all sessions for a user
 func RevokeAllSessionsForUser(userID string) ([]string, error) {
-	return core.RevokeAllSessionsForUser(userID)
+	revokedSessionHandles, err := core.RevokeAllSessionsForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, sessionHandle := range revokedSessionHandles {
+		sessionRevoked(core.SessionEvent{
+			SessionHandle: sessionHandle,
+			UserID:        userID,
+		})
+	}
+	return revokedSessionHandles, nil
 }
 
 // GetAllSessionHandlesForUser function used to get all sessions for a user
@@ -294,14 +558,126 @@ func GetAllSessionHandlesForUser(userID string) ([]string, error) {
 	return core.GetAllSessionHandlesForUser(userID)
 }
 
+// GetAllSessionsForUser function used to get information about all sessions of a user
+func GetAllSessionsForUser(userID string) ([]core.SessionInformation, error) {
+	return core.GetAllSessionsForUser(userID)
+}
+
+// GetSessionInformation function used to get the user ID, session data, jwt payload and lifetime of a session
+func GetSessionInformation(sessionHandle string) (core.SessionInformation, error) {
+	return core.GetSessionInformation(sessionHandle)
+}
+
+// sessionRevoked closes the tracked connections of a revoked session before calling the hook
+func sessionRevoked(event core.SessionEvent) {
+	getConnectionTrackerInstance().closeConnections(event.SessionHandle)
+	core.GetSessionHooksInstance().OnSessionRevokedHook(event)
+}
+
 // RevokeSession function used to revoke a specific session
 func RevokeSession(sessionHandle string) (bool, error) {
-	return core.RevokeSession(sessionHandle)
+	return revokeSession(sessionHandle, "", nil)
+}
+
+func revokeSession(sessionHandle string, userID string, request RequestWrapper) (bool, error) {
+	success, err := core.RevokeSession(sessionHandle)
+	if err != nil {
+		return false, err
+	}
+	if success {
+		sessionRevoked(core.SessionEvent{
+			SessionHandle:  sessionHandle,
+			UserID:         userID,
+			Request:        getHTTPRequest(request),
+			RequestWrapper: request,
+		})
+	}
+	return success, nil
 }
 
 // RevokeMultipleSessions function used to revoke a list of sessions
 func RevokeMultipleSessions(sessionHandles []string) ([]string, error) {
-	return core.RevokeMultipleSessions(sessionHandles)
+	revokedSessionHandles, err := core.RevokeMultipleSessions(sessionHandles)
+	if err != nil {
+		return nil, err
+	}
+	for _, sessionHandle := range revokedSessionHandles {
+		sessionRevoked(core.SessionEvent{
+			SessionHandle: sessionHandle,
+		})
+	}
+	return revokedSessionHandles, nil
+}
+
+// revocationChunkSize is the max number of sessions revoked per request, and of users whose
+// sessions are revoked at the same time
+const revocationChunkSize = 50
+
+// RevokeSessionsForUsers function used to revoke all sessions of many users. Returns the revoked session
+// handles of each user. If this fails for some users, the others are still revoked and a PartialFailureError
+// is returned along with their results
+func RevokeSessionsForUsers(userIDs []string) (map[string][]string, error) {
+	results := map[string][]string{}
+	failures := map[string]error{}
+	var lock sync.Mutex
+	for start := 0; start < len(userIDs); start += revocationChunkSize {
+		end := start + revocationChunkSize
+		if end > len(userIDs) {
+			end = len(userIDs)
+		}
+		var wg sync.WaitGroup
+		for _, userID := range userIDs[start:end] {
+			wg.Add(1)
+			go func(userID string) {
+				defer wg.Done()
+				revokedSessionHandles, err := RevokeAllSessionsForUser(userID)
+				lock.Lock()
+				defer lock.Unlock()
+				if err != nil {
+					failures[userID] = err
+					return
+				}
+				results[userID] = revokedSessionHandles
+			}(userID)
+		}
+		wg.Wait()
+	}
+	if len(failures) != 0 {
+		return results, errors.PartialFailureError{
+			Msg: "could not revoke sessions of " + strconv.Itoa(len(failures)) + " of " +
+				strconv.Itoa(len(userIDs)) + " users",
+			Errors: failures,
+		}
+	}
+	return results, nil
+}
+
+// revokeMultipleSessionsInChunks revokes all sessionHandles even if revoking some of them fails
+func revokeMultipleSessionsInChunks(sessionHandles []string) ([]string, error) {
+	revokedSessionHandles := []string{}
+	failures := map[string]error{}
+	for start := 0; start < len(sessionHandles); start += revocationChunkSize {
+		end := start + revocationChunkSize
+		if end > len(sessionHandles) {
+			end = len(sessionHandles)
+		}
+		revoked, err := RevokeMultipleSessions(sessionHandles[start:end])
+		if err != nil {
+			for _, sessionHandle := range sessionHandles[start:end] {
+				failures[sessionHandle] = err
+			}
+			continue
+		}
+		revokedSessionHandles = append(revokedSessionHandles, revoked...)
+	}
+	if len(failures) != 0 {
+		return revokedSessionHandles, errors.PartialFailureError{
+			Msg: "could not revoke " + strconv.Itoa(len(failures)) + " of " +
+				strconv.Itoa(len(sessionHandles)) + " sessions",
+			Errors: failures,
+		}
+	}
+	return revokedSessionHandles, nil
 }
 
 // GetSessionData function used to get session data for the given handle
@@ -316,9 +692,27 @@ func UpdateSessionData(sessionHandle string, newSessionData map[string]interface
 
 // SetRelevantHeadersForOptionsAPI function is used to set headers specific to SuperTokens for OPTIONS API
 func SetRelevantHeadersForOptionsAPI(response http.ResponseWriter) {
+	setRelevantHeadersForOptionsAPI(wrapResponse(response))
+}
+
+// SetRelevantHeadersForOptionsAPIWithWrapper function is used to set headers specific to SuperTokens for OPTIONS API
+// for frameworks that are not built on net/http
+func SetRelevantHeadersForOptionsAPIWithWrapper(response ResponseWrapper) {
 	setRelevantHeadersForOptionsAPI(response)
 }
 
+// GetRefreshAPIPath function used to get the path of the refresh API, which is RefreshAPIPath if it is configured
+func GetRefreshAPIPath() (string, error) {
+	if configMap != nil && configMap.RefreshAPIPath != "" {
+		return configMap.RefreshAPIPath, nil
+	}
+	handshakeInfo, handshakeInfoError := core.GetHandshakeInfoInstance()
+	if handshakeInfoError != nil {
+		return "", handshakeInfoError
+	}
+	return handshakeInfo.RefreshTokenPath, nil
+}
+
 // GetCORSAllowedHeaders function is used to get header keys that are used by SuperTokens
 func GetCORSAllowedHeaders() []string {
 	return getCORSAllowedHeaders()
@@ -331,7 +725,24 @@ func GetJWTPayload(sessionHandle string) (map[string]interface{}, error) {
 
 // UpdateJWTPayload function used to update jwt payload for the given handle
 func UpdateJWTPayload(sessionHandle string, newJWTPayload map[string]interface{}) error {
-	return core.UpdateJWTPayload(sessionHandle, newJWTPayload)
+	// the current payload is read for its impersonation keys. The lock keeps another modification of the session
+	// in this process from being lost between reading and writing it
+	unlock := lockSession(sessionHandle)
+	defer unlock()
+	currentJWTPayload, err := core.GetJWTPayload(sessionHandle)
+	if err != nil {
+		return err
+	}
+	newJWTPayload = keepImpersonation(currentJWTPayload, newJWTPayload)
+	err = core.UpdateJWTPayload(sessionHandle, newJWTPayload)
+	if err != nil {
+		return err
+	}
+	core.GetSessionHooksInstance().OnJWTPayloadUpdatedHook(core.SessionEvent{
+		SessionHandle: sessionHandle,
+		JWTPayload:    newJWTPayload,
+	})
+	return nil
 }
 
 // OnTokenTheftDetected function to override default behaviour of handling token thefts
@@ -354,6 +765,56 @@ func OnGeneralError(handler func(error, http.ResponseWriter)) {
 	core.GetErrorHandlersInstance().OnGeneralErrorHandler = handler
 }
 
+// OnForbidden function to override default behaviour of handling forbidden errors
+func OnForbidden(handler func(error, http.ResponseWriter)) {
+	core.GetErrorHandlersInstance().OnForbiddenErrorHandler = handler
+}
+
+// OnInvalidClaim function to override default behaviour of handling invalid claim errors
+func OnInvalidClaim(handler func(error, http.ResponseWriter)) {
+	core.GetErrorHandlersInstance().OnInvalidClaimErrorHandler = handler
+}
+
+// OnReauthenticationRequired function to override default behaviour of handling reauthentication required errors
+func OnReauthenticationRequired(handler func(error, http.ResponseWriter)) {
+	core.GetErrorHandlersInstance().OnReauthenticationRequiredHandler = handler
+}
+
+// OnImpersonation function to get notified when an impersonation session is created, expires or is blocked
+func OnImpersonation(hook func(core.ImpersonationEvent)) {
+	core.GetSessionHooksInstance().OnImpersonationHook = hook
+}
+
+// OnSessionCreated function to get notified after a new session has been created
+func OnSessionCreated(hook func(core.SessionEvent)) {
+	core.GetSessionHooksInstance().OnSessionCreatedHook = hook
+}
+
+// OnSessionRefreshed function to get notified after a session has been refreshed
+func OnSessionRefreshed(hook func(core.SessionEvent)) {
+	core.GetSessionHooksInstance().OnSessionRefreshedHook = hook
+}
+
+// OnSessionRevoked function to get notified after a session has been revoked
+func OnSessionRevoked(hook func(core.SessionEvent)) {
+	core.GetSessionHooksInstance().OnSessionRevokedHook = hook
+}
+
+// OnJWTPayloadUpdated function to get notified after the jwt payload of a session has been updated
+func OnJWTPayloadUpdated(hook func(core.SessionEvent)) {
+	core.GetSessionHooksInstance().OnJWTPayloadUpdatedHook = hook
+}
+
+// OnDegradedModeChange function to get notified when sessions start or stop being verified without the core
+func OnDegradedModeChange(handler func(active bool, err error)) {
+	core.GetDegradedModeInstance().SetOnChangeHandler(handler)
+}
+
+// GetDegradedModeStats function used to get counters about sessions verified without the core
+func GetDegradedModeStats() core.DegradedModeStats {
+	return core.GetDegradedModeInstance().GetStats()
+}
+
 // GetSessionFromRequest returns the verified session object if present, otherwise returns nil
 func GetSessionFromRequest(r *http.Request) *Session {
 	value := r.Context().Value(sessionContext)
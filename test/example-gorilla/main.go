@@ -42,12 +42,8 @@ func main() {
 	r.HandleFunc("/login", login)
 	r.HandleFunc("/testUserConfig", testUserConfig)
 	r.HandleFunc("/multipleInterceptors", multipleInterceptors)
-	r.HandleFunc("/", supertokens.Middleware(defaultHandler))
 	r.HandleFunc("/beforeeach", beforeeach)
 	r.HandleFunc("/testing", testing)
-	r.HandleFunc("/logout", supertokens.Middleware(logout))
-	r.HandleFunc("/revokeAll", supertokens.Middleware(revokeAll))
-	r.HandleFunc("/refresh", supertokens.Middleware(refresh))
 	r.HandleFunc("/refreshCalledTime", refreshCalledTime)
 	r.HandleFunc("/getSessionCalledTime", getSessionCalledTime)
 	r.HandleFunc("/ping", ping)
@@ -57,7 +53,14 @@ func main() {
 	r.HandleFunc("/testError", testError)
 	r.HandleFunc("/index.html", index)
 	r.HandleFunc("/fail", fail)
-	r.HandleFunc("/update-jwt", supertokens.Middleware(updateJwt))
+
+	sessionRoutes := r.NewRoute().Subrouter()
+	sessionRoutes.Use(supertokens.NewMiddleware())
+	sessionRoutes.HandleFunc("/", defaultHandler)
+	sessionRoutes.HandleFunc("/logout", logout)
+	sessionRoutes.HandleFunc("/revokeAll", revokeAll)
+	sessionRoutes.HandleFunc("/refresh", refresh)
+	sessionRoutes.HandleFunc("/update-jwt", updateJwt)
 	supertokens.OnTryRefreshToken(customOnTryRefreshTokenError)
 	supertokens.OnUnauthorized(customOnUnauthorizedError)
 	supertokens.OnGeneralError(customOnGeneralError)
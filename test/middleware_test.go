@@ -17,12 +17,15 @@
 package testing
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/supertokens/supertokens-go/supertokens"
 )
@@ -274,3 +277,351 @@ func TestMiddleware(t *testing.T) {
 		t.Error("incorrect status code")
 	}
 }
+
+func TestMiddlewareRefreshHint(t *testing.T) {
+	beforeEach()
+	startST("localhost", "8080")
+	supertokens.Config(supertokens.ConfigMap{
+		Hosts:             "http://localhost:8080",
+		RefreshHintWindow: 24 * time.Hour,
+	})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/create", func(response http.ResponseWriter, request *http.Request) {
+		supertokens.CreateNewSession(response, "testing-userID")
+	})
+	mux.HandleFunc("/user/id", supertokens.Middleware(func(response http.ResponseWriter, request *http.Request) {
+	}))
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	client := &http.Client{}
+	req, _ := http.NewRequest("POST", ts.URL+"/create", nil)
+	res, _ := client.Do(req)
+
+	response := extractInfoFromResponseHeader(res)
+	req, _ = http.NewRequest("POST", ts.URL+"/user/id", nil)
+	req.Header.Add("Cookie", "sAccessToken="+response["accessToken"]+";sIdRefreshToken="+response["idRefreshTokenFromCookie"])
+	req.Header.Add("anti-csrf", response["antiCsrf"])
+	res, _ = client.Do(req)
+
+	if res.StatusCode != 200 {
+		t.Error("response has non 200 status code")
+	}
+	if res.Header.Get("st-refresh-recommended") != "true" {
+		t.Error("refresh was not recommended")
+	}
+	if res.Header.Get("st-access-token-expiry") == "" {
+		t.Error("access token expiry header is missing")
+	}
+}
+
+func TestNewMiddlewareWithOptions(t *testing.T) {
+	beforeEach()
+	startST("localhost", "8080")
+	supertokens.Config(supertokens.ConfigMap{
+		Hosts: "http://localhost:8080",
+	})
+	doAntiCsrfCheck := false
+	required := supertokens.NewMiddleware(supertokens.MiddlewareOptions{
+		AntiCsrfCheck: &doAntiCsrfCheck,
+		OnError: func(err error, response http.ResponseWriter) {
+			response.WriteHeader(418)
+		},
+	})
+	optional := supertokens.NewMiddleware(supertokens.MiddlewareOptions{
+		SessionOptional: true,
+	})
+	userID := http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		session := supertokens.GetSessionFromRequest(request)
+		if session == nil {
+			response.Write([]byte("anonymous"))
+			return
+		}
+		response.Write([]byte(session.GetUserID()))
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/create", func(response http.ResponseWriter, request *http.Request) {
+		supertokens.CreateNewSession(response, "testing-userID")
+	})
+	mux.Handle("/required", required(userID))
+	mux.Handle("/optional", optional(userID))
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	client := &http.Client{}
+	req, _ := http.NewRequest("POST", ts.URL+"/create", nil)
+	res, _ := client.Do(req)
+	response := extractInfoFromResponseHeader(res)
+
+	readBody := func(res *http.Response) string {
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Error("error when parsing body")
+		}
+		return string(body)
+	}
+
+	// the anti-csrf check is disabled for this route, so the header is not needed
+	req, _ = http.NewRequest("POST", ts.URL+"/required", nil)
+	req.Header.Add("Cookie", "sAccessToken="+response["accessToken"]+";sIdRefreshToken="+response["idRefreshTokenFromCookie"])
+	res, _ = client.Do(req)
+	if res.StatusCode != 200 || readBody(res) != "testing-userID" {
+		t.Error("session was not verified without anti-csrf check")
+	}
+
+	req, _ = http.NewRequest("POST", ts.URL+"/required", nil)
+	res, _ = client.Do(req)
+	if res.StatusCode != 418 {
+		t.Error("custom error handler was not used")
+	}
+	res.Body.Close()
+
+	req, _ = http.NewRequest("GET", ts.URL+"/optional", nil)
+	res, _ = client.Do(req)
+	if res.StatusCode != 200 || readBody(res) != "anonymous" {
+		t.Error("request without session was not let through")
+	}
+
+	req, _ = http.NewRequest("GET", ts.URL+"/optional", nil)
+	req.Header.Add("Cookie", "sAccessToken="+response["accessToken"]+";sIdRefreshToken="+response["idRefreshTokenFromCookie"])
+	res, _ = client.Do(req)
+	if res.StatusCode != 200 || readBody(res) != "testing-userID" {
+		t.Error("optional session was not verified")
+	}
+
+	// an expired access token still needs a refresh
+	req, _ = http.NewRequest("GET", ts.URL+"/optional", nil)
+	req.Header.Add("Cookie", "sIdRefreshToken="+response["idRefreshTokenFromCookie"])
+	res, _ = client.Do(req)
+	if res.StatusCode == 200 {
+		t.Error("try refresh token error was not returned")
+	}
+	res.Body.Close()
+}
+
+func TestRequireRole(t *testing.T) {
+	beforeEach()
+	startST("localhost", "8080")
+	supertokens.Config(supertokens.ConfigMap{
+		Hosts:          "http://localhost:8080",
+		RolesClaimPath: "auth.roles",
+	})
+	doAntiCsrfCheck := false
+	verify := supertokens.NewMiddleware(supertokens.MiddlewareOptions{
+		AntiCsrfCheck: &doAntiCsrfCheck,
+	})
+	ok := http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		response.Write([]byte("ok"))
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/create", func(response http.ResponseWriter, request *http.Request) {
+		session, _ := supertokens.CreateNewSession(response, "testing-userID", map[string]interface{}{
+			"auth": map[string]interface{}{"roles": []string{"support", "billing"}},
+			"plan": "pro",
+		})
+		if !session.HasAnyRole("admin", "support") || session.HasAllRoles("support", "admin") {
+			t.Error("incorrect roles of new session", session.GetRoles())
+		}
+	})
+	mux.Handle("/support", verify(supertokens.RequireRole("support")(ok)))
+	mux.Handle("/admin", verify(supertokens.RequireRole("admin")(ok)))
+	mux.Handle("/pro", verify(supertokens.RequireClaims(supertokens.ClaimEquals("plan", "pro"))(ok)))
+	mux.Handle("/unprotected", supertokens.RequireRole("support")(ok))
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	client := &http.Client{}
+	req, _ := http.NewRequest("POST", ts.URL+"/create", nil)
+	res, _ := client.Do(req)
+	response := extractInfoFromResponseHeader(res)
+
+	statusOf := func(path string, withSession bool) int {
+		req, _ := http.NewRequest("GET", ts.URL+path, nil)
+		if withSession {
+			req.Header.Add("Cookie", "sAccessToken="+response["accessToken"]+";sIdRefreshToken="+response["idRefreshTokenFromCookie"])
+		}
+		res, _ := client.Do(req)
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	if statusOf("/support", true) != 200 || statusOf("/pro", true) != 200 {
+		t.Error("allowed request was not let through")
+	}
+	if statusOf("/admin", true) != 403 {
+		t.Error("forbidden error was not returned")
+	}
+	if status := statusOf("/unprotected", false); status != 440 && status != 401 {
+		t.Error("unauthorised error was not returned without a session")
+	}
+
+	supertokens.OnForbidden(func(err error, response http.ResponseWriter) {
+		response.WriteHeader(418)
+	})
+	if statusOf("/admin", true) != 418 {
+		t.Error("custom forbidden handler was not used")
+	}
+}
+
+func TestSessionClaims(t *testing.T) {
+	beforeEach()
+	startST("localhost", "8080")
+	subscription := "free"
+	fetchCount := 0
+	supertokens.Config(supertokens.ConfigMap{
+		Hosts: "http://localhost:8080",
+		SessionClaims: []supertokens.SessionClaim{{
+			Key: "plan",
+			Fetch: func(ctx context.Context, userID string) (interface{}, error) {
+				fetchCount++
+				return subscription, nil
+			},
+			MaxAge: time.Second,
+			Validate: func(value interface{}) error {
+				if value != "pro" {
+					return errors.New("a pro subscription is required")
+				}
+				return nil
+			},
+		}},
+	})
+	doAntiCsrfCheck := false
+	verify := supertokens.NewMiddleware(supertokens.MiddlewareOptions{
+		AntiCsrfCheck: &doAntiCsrfCheck,
+	})
+	skipClaims := supertokens.NewMiddleware(supertokens.MiddlewareOptions{
+		AntiCsrfCheck:       &doAntiCsrfCheck,
+		SkipClaimValidation: true,
+	})
+	ok := http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		response.Write([]byte("ok"))
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/create", func(response http.ResponseWriter, request *http.Request) {
+		supertokens.CreateNewSession(response, "testing-userID")
+	})
+	mux.Handle("/pro", verify(ok))
+	mux.Handle("/upgrade", skipClaims(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		subscription = "pro"
+	})))
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	client := &http.Client{}
+	req, _ := http.NewRequest("POST", ts.URL+"/create", nil)
+	res, _ := client.Do(req)
+	response := extractInfoFromResponseHeader(res)
+	accessToken := response["accessToken"]
+
+	statusOf := func(path string) int {
+		req, _ := http.NewRequest("GET", ts.URL+path, nil)
+		req.Header.Add("Cookie", "sAccessToken="+accessToken+";sIdRefreshToken="+response["idRefreshTokenFromCookie"])
+		res, _ := client.Do(req)
+		res.Body.Close()
+		// refetched claims come with a new access token
+		for _, cookie := range res.Cookies() {
+			if cookie.Name == "sAccessToken" {
+				accessToken = cookie.Value
+			}
+		}
+		return res.StatusCode
+	}
+
+	if statusOf("/pro") != 403 {
+		t.Error("invalid claim error was not returned")
+	}
+	if statusOf("/upgrade") != 200 {
+		t.Error("claim validation was not skipped")
+	}
+	time.Sleep(1500 * time.Millisecond)
+	if statusOf("/pro") != 200 {
+		t.Error("stale claim was not refetched")
+	}
+	if fetchCount != 2 {
+		t.Error("incorrect number of fetches", fetchCount)
+	}
+}
+
+func TestRequireRecentAuth(t *testing.T) {
+	beforeEach()
+	startST("localhost", "8080")
+	supertokens.Config(supertokens.ConfigMap{
+		Hosts:                "http://localhost:8080",
+		RecordAuthentication: true,
+	})
+	doAntiCsrfCheck := false
+	verify := supertokens.NewMiddleware(supertokens.MiddlewareOptions{
+		AntiCsrfCheck: &doAntiCsrfCheck,
+	})
+	ok := http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		response.Write([]byte("ok"))
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/create", func(response http.ResponseWriter, request *http.Request) {
+		session, _ := supertokens.CreateNewSession(response, "testing-userID", map[string]interface{}{"key": "value"})
+		if session.GetAssuranceLevel() != supertokens.DefaultAssuranceLevel ||
+			time.Since(session.GetAuthTime()) > time.Minute || session.GetJWTPayload()["key"] != "value" {
+			t.Error("authentication was not recorded", session.GetJWTPayload())
+		}
+	})
+	mux.Handle("/billing", verify(supertokens.RequireRecentAuth(time.Hour, 2)(ok)))
+	mux.Handle("/password", verify(supertokens.RequireRecentAuth(time.Second)(ok)))
+	mux.Handle("/second-factor", verify(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		if err := supertokens.GetSessionFromRequest(request).MarkReauthenticated(2); err != nil {
+			response.WriteHeader(500)
+		}
+	})))
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	client := &http.Client{}
+	req, _ := http.NewRequest("POST", ts.URL+"/create", nil)
+	res, _ := client.Do(req)
+	response := extractInfoFromResponseHeader(res)
+	accessToken := response["accessToken"]
+
+	statusOf := func(method string, path string) int {
+		req, _ := http.NewRequest(method, ts.URL+path, nil)
+		req.Header.Add("Cookie", "sAccessToken="+accessToken+";sIdRefreshToken="+response["idRefreshTokenFromCookie"])
+		res, _ := client.Do(req)
+		res.Body.Close()
+		for _, cookie := range res.Cookies() {
+			if cookie.Name == "sAccessToken" {
+				accessToken = cookie.Value
+			}
+		}
+		return res.StatusCode
+	}
+
+	if statusOf("GET", "/billing") != 403 {
+		t.Error("low assurance level was accepted")
+	}
+	time.Sleep(1500 * time.Millisecond)
+	if statusOf("GET", "/password") != 403 {
+		t.Error("stale authentication was accepted")
+	}
+
+	supertokens.OnReauthenticationRequired(func(err error, response http.ResponseWriter) {
+		response.WriteHeader(418)
+	})
+	if statusOf("GET", "/password") != 418 {
+		t.Error("custom reauthentication required handler was not used")
+	}
+
+	if statusOf("POST", "/second-factor") != 200 {
+		t.Error("marking the session as reauthenticated failed")
+	}
+	if statusOf("GET", "/billing") != 200 || statusOf("GET", "/password") != 200 {
+		t.Error("reauthenticated session was not let through")
+	}
+}
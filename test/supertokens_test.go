@@ -16,12 +16,16 @@
 package testing
 
 import (
+	"context"
 	"encoding/json"
+	goErrors "errors"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/supertokens/supertokens-go/supertokens"
 	"github.com/supertokens/supertokens-go/supertokens/core"
@@ -855,3 +859,524 @@ func TestAntiCsrfDisabled(t *testing.T) {
 		}
 	}
 }
+
+func TestAccessTokenClaims(t *testing.T) {
+	beforeEach()
+	startST("localhost", "8080")
+	supertokens.Config(supertokens.ConfigMap{
+		Hosts: "http://localhost:8080",
+	})
+
+	session, err := supertokens.CreateNewSession(httptest.NewRecorder(), "id1")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if session.GetAccessTokenPayload()["sessionHandle"] != session.GetHandle() {
+		t.Error("incorrect access token payload")
+	}
+	if session.GetAccessTokenExpiry() <= getCurrTimeInMS() {
+		t.Error("access token expiry is not in the future")
+	}
+	if session.GetTimeCreated() == 0 || session.GetTimeCreated() > getCurrTimeInMS() {
+		t.Error("incorrect time created")
+	}
+	if session.GetTimeUntilExpiry() <= 0 {
+		t.Error("time until expiry is not positive")
+	}
+}
+
+func TestSessionLifecycleHooks(t *testing.T) {
+	beforeEach()
+	startST("localhost", "8080")
+	supertokens.Config(supertokens.ConfigMap{
+		Hosts: "http://localhost:8080",
+	})
+
+	events := []string{}
+	var createdEvent core.SessionEvent
+	supertokens.OnSessionCreated(func(event core.SessionEvent) {
+		events = append(events, "created")
+		createdEvent = event
+	})
+	supertokens.OnJWTPayloadUpdated(func(event core.SessionEvent) {
+		events = append(events, "jwtPayloadUpdated")
+	})
+	supertokens.OnSessionRevoked(func(event core.SessionEvent) {
+		events = append(events, "revoked:"+event.UserID)
+	})
+
+	request := httptest.NewRequest("POST", "/create", nil)
+	session, err := supertokens.CreateNewSessionWithRequest(httptest.NewRecorder(), request, "id1",
+		map[string]interface{}{"role": "admin"}, map[string]interface{}{"key": "value"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if createdEvent.SessionHandle != session.GetHandle() || createdEvent.UserID != "id1" ||
+		createdEvent.Request != request || createdEvent.JWTPayload["role"] != "admin" ||
+		createdEvent.SessionData["key"] != "value" {
+		t.Error("incorrect created event", createdEvent)
+	}
+
+	if err := session.UpdateJWTPayload(map[string]interface{}{"role": "user"}); err != nil {
+		t.Error(err)
+	}
+	if err := session.RevokeSession(); err != nil {
+		t.Error(err)
+	}
+	if _, err := supertokens.RevokeSession(session.GetHandle()); err != nil {
+		t.Error(err)
+	}
+
+	if len(events) != 3 || events[0] != "created" || events[1] != "jwtPayloadUpdated" || events[2] != "revoked:id1" {
+		t.Error("incorrect events", events)
+	}
+}
+
+func TestTokenTheftPolicy(t *testing.T) {
+	beforeEach()
+	startST("localhost", "8080")
+	notified := make(chan core.TokenTheftEvent, 1)
+	supertokens.Config(supertokens.ConfigMap{
+		Hosts: "http://localhost:8080",
+		TokenTheftPolicy: &core.TokenTheftPolicy{
+			RevokeAllSessionsForUser: true,
+			Notify: func(event core.TokenTheftEvent, revokeError error) {
+				if revokeError != nil {
+					t.Error(revokeError)
+				}
+				notified <- event
+			},
+			RateLimitDuration: time.Minute,
+		},
+	})
+	supertokens.RevokeAllSessionsForUser("id2")
+
+	createResponse := httptest.NewRecorder()
+	if _, err := supertokens.CreateNewSession(createResponse, "id2"); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := supertokens.CreateNewSession(httptest.NewRecorder(), "id2"); err != nil {
+		t.Error(err)
+		return
+	}
+	var refreshToken string
+	for _, cookie := range createResponse.Result().Cookies() {
+		if cookie.Name == "sRefreshToken" {
+			refreshToken = cookie.Value
+		}
+	}
+	refresh := func() error {
+		request := httptest.NewRequest("POST", "/session/refresh", nil)
+		request.Header.Add("Cookie", "sRefreshToken="+refreshToken)
+		request.Header.Add("anti-csrf", createResponse.Header().Get("anti-csrf"))
+		request.Header.Set("User-Agent", "test-agent")
+		_, err := supertokens.RefreshSession(httptest.NewRecorder(), request)
+		return err
+	}
+	if err := refresh(); err != nil {
+		t.Error(err)
+		return
+	}
+	// the new access token is never used, so refreshing with the old refresh token again is detected as theft
+	if err := refresh(); !errors.IsTokenTheftDetectedError(err) {
+		t.Error("token theft was not detected", err)
+		return
+	}
+
+	select {
+	case event := <-notified:
+		if event.UserID != "id2" || event.UserAgent != "test-agent" || event.RemoteAddr == "" {
+			t.Error("incorrect token theft event", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("token theft policy was not applied")
+		return
+	}
+
+	sessionHandles, err := supertokens.GetAllSessionHandlesForUser("id2")
+	if err != nil || len(sessionHandles) != 0 {
+		t.Error("sessions of the user were not revoked", sessionHandles, err)
+	}
+	if _, err := supertokens.CreateNewSession(httptest.NewRecorder(), "id2"); !errors.IsUnauthorizedError(err) {
+		t.Error("new sessions of the user were not rate limited", err)
+	}
+	if _, err := supertokens.CreateNewSession(httptest.NewRecorder(), "id3"); err != nil {
+		t.Error("other users should not be rate limited", err)
+	}
+}
+
+func TestGetAllSessionsForUser(t *testing.T) {
+	beforeEach()
+	startST("localhost", "8080")
+	supertokens.Config(supertokens.ConfigMap{
+		Hosts: "http://localhost:8080",
+	})
+	supertokens.RevokeAllSessionsForUser("id1")
+
+	for i := 0; i < 3; i++ {
+		_, err := supertokens.CreateNewSession(httptest.NewRecorder(), "id1",
+			map[string]interface{}{"index": i}, map[string]interface{}{"index": i})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	sessions, err := supertokens.GetAllSessionsForUser("id1")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(sessions) != 3 {
+		t.Error("incorrect number of sessions", len(sessions))
+	}
+	for _, session := range sessions {
+		if session.UserID != "id1" || session.SessionData["index"] != session.JWTPayload["index"] {
+			t.Error("incorrect session information", session)
+		}
+	}
+
+	information, err := supertokens.GetSessionInformation(sessions[0].Handle)
+	if err != nil || information.SessionData["index"] != sessions[0].SessionData["index"] {
+		t.Error("incorrect session information", information, err)
+	}
+}
+
+func TestDeviceMetadata(t *testing.T) {
+	beforeEach()
+	startST("localhost", "8080")
+	supertokens.Config(supertokens.ConfigMap{
+		Hosts:                   "http://localhost:8080",
+		DeviceMetadataExtractor: supertokens.DefaultDeviceMetadataExtractor,
+	})
+
+	request := httptest.NewRequest("POST", "/login", nil)
+	request.RemoteAddr = "192.0.2.1:1234"
+	request.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 "+
+		"(KHTML, like Gecko) Chrome/85.0.4183.102 Safari/537.36")
+	session, err := supertokens.CreateNewSessionWithRequest(httptest.NewRecorder(), request, "id1",
+		nil, map[string]interface{}{"key": "value"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	metadata, err := session.GetDeviceMetadata()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if metadata == nil || metadata.IPAddress != "192.0.2.1" || metadata.Label != "Chrome on macOS" ||
+		metadata.UserAgent != request.UserAgent() {
+		t.Error("incorrect device metadata", metadata)
+	}
+	data, _ := session.GetSessionData()
+	if data["key"] != "value" {
+		t.Error("session data was not kept")
+	}
+
+	session, err = supertokens.CreateNewSession(httptest.NewRecorder(), "id1")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if metadata, _ := session.GetDeviceMetadata(); metadata != nil {
+		t.Error("device metadata should not be stored without a request", metadata)
+	}
+}
+
+func TestBulkRevocation(t *testing.T) {
+	beforeEach()
+	startST("localhost", "8080")
+	supertokens.Config(supertokens.ConfigMap{
+		Hosts: "http://localhost:8080",
+	})
+	supertokens.RevokeSessionsForUsers([]string{"id1", "id2"})
+
+	session, err := supertokens.CreateNewSession(httptest.NewRecorder(), "id1")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for i := 0; i < 2; i++ {
+		supertokens.CreateNewSession(httptest.NewRecorder(), "id1")
+		supertokens.CreateNewSession(httptest.NewRecorder(), "id2")
+	}
+
+	revoked, err := session.RevokeAllOtherSessions()
+	if err != nil || len(revoked) != 2 {
+		t.Error("other sessions were not revoked", revoked, err)
+	}
+	sessionHandles, _ := supertokens.GetAllSessionHandlesForUser("id1")
+	if len(sessionHandles) != 1 || sessionHandles[0] != session.GetHandle() {
+		t.Error("current session should not be revoked", sessionHandles)
+	}
+
+	results, err := supertokens.RevokeSessionsForUsers([]string{"id1", "id2", "id3"})
+	if err != nil {
+		t.Error(err)
+	}
+	if len(results["id1"]) != 1 || len(results["id2"]) != 2 || len(results["id3"]) != 0 {
+		t.Error("incorrect revocation results", results)
+	}
+
+	killAllST()
+	_, err = supertokens.RevokeSessionsForUsers([]string{"id1", "id2"})
+	if !errors.IsPartialFailureError(err) || len(err.(errors.PartialFailureError).Errors) != 2 {
+		t.Error("failures should be aggregated", err)
+	}
+}
+
+func TestMergingSessionDataAndJWTPayload(t *testing.T) {
+	beforeEach()
+	startST("localhost", "8080")
+	supertokens.Config(supertokens.ConfigMap{
+		Hosts: "http://localhost:8080",
+	})
+
+	session, err := supertokens.CreateNewSession(httptest.NewRecorder(), "id1",
+		map[string]interface{}{"role": "admin", "tenant": "t1"},
+		map[string]interface{}{"nested": map[string]interface{}{"a": 1, "b": 2}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int, session supertokens.Session) {
+			defer wg.Done()
+			err := session.MergeSessionData(map[string]interface{}{"key" + strconv.Itoa(i): i})
+			if err != nil {
+				t.Error(err)
+			}
+		}(i, session)
+	}
+	wg.Wait()
+	err = session.MergeSessionData(map[string]interface{}{"nested": map[string]interface{}{"a": nil, "c": 3}})
+	if err != nil {
+		t.Error(err)
+	}
+
+	data, _ := session.GetSessionData()
+	for i := 0; i < 5; i++ {
+		if data["key"+strconv.Itoa(i)] != float64(i) {
+			t.Error("concurrent change was lost", data)
+		}
+	}
+	nested := data["nested"].(map[string]interface{})
+	if _, ok := nested["a"]; ok || nested["b"] != float64(2) || nested["c"] != float64(3) {
+		t.Error("incorrect merge of nested data", nested)
+	}
+
+	err = session.ModifySessionData(func(data map[string]interface{}) error {
+		data["key0"] = "changed"
+		return goErrors.New("abort")
+	})
+	if err == nil || err.Error() != "abort" {
+		t.Error("error of modify function should be returned", err)
+	}
+	data, _ = session.GetSessionData()
+	if data["key0"] != float64(0) {
+		t.Error("data should not be saved if modify fails", data)
+	}
+
+	if err = session.MergeJWTPayload(map[string]interface{}{"tenant": nil, "plan": "pro"}); err != nil {
+		t.Error(err)
+	}
+	payload := session.GetJWTPayload()
+	if _, ok := payload["tenant"]; ok || payload["role"] != "admin" || payload["plan"] != "pro" {
+		t.Error("incorrect merge of jwt payload", payload)
+	}
+}
+
+type coreRequestCounter struct {
+	core.MetricsRecorder
+	lock  sync.Mutex
+	paths map[string]int
+}
+
+func (counter *coreRequestCounter) CoreRequestCompleted(host string, path string, statusCode int,
+	duration time.Duration, err error) {
+	counter.lock.Lock()
+	defer counter.lock.Unlock()
+	counter.paths[path]++
+}
+
+func (counter *coreRequestCounter) count(path string) int {
+	counter.lock.Lock()
+	defer counter.lock.Unlock()
+	return counter.paths[path]
+}
+
+func TestSessionDataCaching(t *testing.T) {
+	for _, disabled := range []bool{false, true} {
+		beforeEach()
+		startST("localhost", "8080")
+		counter := &coreRequestCounter{MetricsRecorder: core.GetMetricsRecorder(), paths: map[string]int{}}
+		supertokens.Config(supertokens.ConfigMap{
+			Hosts:                     "http://localhost:8080",
+			MetricsRecorder:           counter,
+			DisableSessionDataCaching: disabled,
+		})
+
+		session, err := supertokens.CreateNewSession(httptest.NewRecorder(), "id1",
+			nil, map[string]interface{}{"key": "value"})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		copyOfSession := session
+		data, _ := session.GetSessionData()
+		data["key"] = "changed by caller"
+		data, _ = copyOfSession.GetSessionData()
+		if data["key"] != "value" {
+			t.Error("incorrect session data", data)
+		}
+		expected := 1
+		if disabled {
+			expected = 2
+		}
+		if counter.count("/session/data") != expected {
+			t.Error("incorrect number of requests to the core", counter.count("/session/data"), disabled)
+		}
+
+		session.UpdateSessionData(map[string]interface{}{"key": "value2"})
+		data, _ = copyOfSession.GetSessionData()
+		if data["key"] != "value2" {
+			t.Error("cache was not cleared by UpdateSessionData", data)
+		}
+		killAllST()
+	}
+}
+
+// testConnection counts how often it was closed
+type testConnection struct {
+	lock   sync.Mutex
+	closed int
+}
+
+func (connection *testConnection) Close() error {
+	connection.lock.Lock()
+	defer connection.lock.Unlock()
+	connection.closed++
+	return nil
+}
+
+func (connection *testConnection) getClosed() int {
+	connection.lock.Lock()
+	defer connection.lock.Unlock()
+	return connection.closed
+}
+
+func TestConnectionTracking(t *testing.T) {
+	beforeEach()
+	startST("localhost", "8080")
+	supertokens.Config(supertokens.ConfigMap{
+		Hosts: "http://localhost:8080",
+	})
+
+	session, err := supertokens.CreateNewSession(httptest.NewRecorder(), "id1")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	revokedConnection := &testConnection{}
+	closedConnection := &testConnection{}
+	supertokens.TrackConnection(session, revokedConnection)
+	untrack := supertokens.TrackConnection(session, closedConnection)
+	if supertokens.GetTrackedConnectionCount(session.GetHandle()) != 2 {
+		t.Error("incorrect number of tracked connections")
+	}
+	untrack()
+
+	_, err = supertokens.RevokeAllSessionsForUser("id1")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if revokedConnection.getClosed() != 1 {
+		t.Error("connection of revoked session was not closed")
+	}
+	if closedConnection.getClosed() != 0 {
+		t.Error("connection that is no longer tracked was closed")
+	}
+	if supertokens.GetTrackedConnectionCount(session.GetHandle()) != 0 {
+		t.Error("revoked session still has tracked connections")
+	}
+}
+
+func TestConnectionReverification(t *testing.T) {
+	beforeEach()
+	setKeyValueInConfig("access_token_blacklisting", "true")
+	startST("localhost", "8080")
+	supertokens.Config(supertokens.ConfigMap{
+		Hosts:                      "http://localhost:8080",
+		ConnectionReverifyInterval: 100 * time.Millisecond,
+	})
+
+	session, err := supertokens.CreateNewSession(httptest.NewRecorder(), "id1")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	connection := &testConnection{}
+	supertokens.TrackConnection(session, connection)
+
+	// revoking with the core directly is like revoking in another process
+	_, err = core.RevokeSession(session.GetHandle())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	time.Sleep(500 * time.Millisecond)
+	if connection.getClosed() != 1 {
+		t.Error("connection of session revoked in another process was not closed")
+	}
+}
+
+func TestImpersonationSession(t *testing.T) {
+	beforeEach()
+	startST("localhost", "8080")
+	supertokens.Config(supertokens.ConfigMap{
+		Hosts:                    "http://localhost:8080",
+		ImpersonationMaxLifetime: time.Second,
+	})
+	var events []core.ImpersonationEvent
+	supertokens.OnImpersonation(func(event core.ImpersonationEvent) {
+		events = append(events, event)
+	})
+
+	response := httptest.NewRecorder()
+	session, err := supertokens.CreateImpersonationSession(response, "testing-userID", "support-userID",
+		map[string]interface{}{"key": "value"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !session.IsImpersonated() || session.GetActorID() != "support-userID" ||
+		session.GetJWTPayload()["key"] != "value" {
+		t.Error("actor was not stored in the jwt payload", session.GetJWTPayload())
+	}
+	if _, err = supertokens.CreateImpersonationSession(httptest.NewRecorder(), "testing-userID",
+		"testing-userID"); err == nil {
+		t.Error("user impersonated themselves")
+	}
+
+	time.Sleep(1500 * time.Millisecond)
+	_, err = supertokens.GetSessionFromAccessToken(context.Background(), session.GetAccessToken(), nil, false)
+	if !errors.IsUnauthorizedError(err) {
+		t.Error("expired impersonation session was accepted", err)
+	}
+	handles, _ := supertokens.GetAllSessionHandlesForUser("testing-userID")
+	if len(handles) != 0 {
+		t.Error("expired impersonation session was not revoked")
+	}
+	if len(events) != 2 || events[0].Type != core.ImpersonationStarted || events[1].Type != core.ImpersonationExpired {
+		t.Error("incorrect impersonation events", events)
+	}
+}
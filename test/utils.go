@@ -24,6 +24,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/supertokens/supertokens-go/supertokens"
 	"github.com/supertokens/supertokens-go/supertokens/core"
 )
 
@@ -113,10 +114,19 @@ func killAllST() {
 	}
 	core.ResetDeviceDriverInfo()
 	core.ResetError()
+	core.ResetSessionHooks()
+	core.ResetTokenTheftPolicy()
 	core.ResetHandshakeInfo()
 	core.ResetQuerier()
 	core.ResetProcessState()
 	core.ResetHTTPMocking()
+	core.ResetVerifyCache()
+	core.ResetDegradedMode()
+	core.ResetHandshakeStore()
+	core.SetLogger(nil)
+	core.SetMetricsRecorder(nil)
+	core.SetTracer(nil)
+	supertokens.ResetConnectionTracker()
 }
 
 func startST(host string, port string) string {